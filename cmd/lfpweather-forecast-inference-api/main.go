@@ -6,16 +6,22 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"alpineworks.io/ootel"
 	"github.com/gorilla/mux"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/config"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/dragonfly"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/handlers"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/leader"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/logging"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/middleware"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/worker"
 )
 
@@ -39,6 +45,10 @@ func main() {
 
 	slog.SetLogLoggerLevel(slogLevel)
 
+	if c.WorkerInterval != 0 {
+		slog.Warn("WORKER_INTERVAL is deprecated, use WORKER_POLL_INTERVAL instead", slog.Duration("worker_poll_interval", c.WorkerPollInterval))
+	}
+
 	ctx := context.Background()
 
 	ootelClient := ootel.NewOotelClient(
@@ -68,63 +78,207 @@ func main() {
 		_ = shutdown(ctx)
 	}()
 
-	// Initialize LLM provider based on configuration
-	var llmProvider llm.Provider
-	switch strings.ToLower(c.LLMProvider) {
-	case "openai":
-		llmProvider = llm.NewOpenAIProvider(c.OpenAIAPIKey, c.OpenAIModel, c.OpenAIBaseURL, c.OpenAINoThink)
-		slog.Info("using OpenAI-compatible provider", slog.String("model", c.OpenAIModel))
-	case "anthropic":
-		fallthrough
+	switch c.OpenAIStructuredOutput {
+	case llm.StructuredOutputJSONSchema, llm.StructuredOutputGrammar, llm.StructuredOutputNone:
 	default:
-		llmProvider = llm.NewAnthropicProvider(c.AnthropicAPIKey, c.AnthropicModel)
-		slog.Info("using Anthropic provider", slog.String("model", c.AnthropicModel))
+		slog.Error("unknown openai structured output mode", slog.String("mode", c.OpenAIStructuredOutput))
+		os.Exit(1)
+	}
+
+	// Initialize the LLM providers based on configuration, in the order they are tried
+	fallbackTargets := make([]llm.FallbackTarget, 0, len(c.LLMProviders))
+	for _, name := range c.LLMProviders {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "openai":
+			fallbackTargets = append(fallbackTargets, llm.FallbackTarget{
+				Provider: llm.NewOpenAIProvider(c.OpenAIAPIKey, c.OpenAIModel, c.OpenAIBaseURL, c.OpenAINoThink, c.OpenAIStructuredOutput),
+				Timeout:  c.OpenAITimeout,
+			})
+			slog.Info("using OpenAI-compatible provider", slog.String("model", c.OpenAIModel))
+		case "anthropic":
+			fallbackTargets = append(fallbackTargets, llm.FallbackTarget{
+				Provider: llm.NewAnthropicProvider(c.AnthropicAPIKey, c.AnthropicModel),
+				Timeout:  c.AnthropicTimeout,
+			})
+			slog.Info("using Anthropic provider", slog.String("model", c.AnthropicModel))
+		default:
+			slog.Error("unknown llm provider", slog.String("provider", name))
+			os.Exit(1)
+		}
+	}
+
+	llmProvider, err := llm.NewFallbackProvider(fallbackTargets, c.LLMCircuitBreakerFailures, c.LLMCircuitBreakerCooldown)
+	if err != nil {
+		slog.Error("could not create llm provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	promptRegistry, err := prompts.NewRegistry(c.PromptsDir)
+	if err != nil {
+		slog.Error("could not load prompt templates", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// without a directory there is nothing to reload, the embedded templates only change with a rebuild
+	if c.PromptsDir != "" && c.PromptsReloadInterval > 0 {
+		go promptRegistry.Watch(ctx, c.PromptsReloadInterval)
+		slog.Info("reloading prompt templates", slog.String("dir", c.PromptsDir), slog.String("interval", c.PromptsReloadInterval.String()))
 	}
 
-	nwsClient := nws.NewNWSClient(&http.Client{
-		Timeout: c.NWSClientTimeout,
-	})
+	defaultGridPoint, err := nws.ParseGridPoint(c.GridPoint)
+	if err != nil {
+		slog.Error("could not parse grid point", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	dragonflyClient, err := dragonfly.NewDragonflyClient(
-		c.DragonflyHost,
-		c.DragonflyPort,
-		c.DragonflyAuth,
-		c.CacheResultsDuration,
-		c.DragonflyKeyPrefix,
+	nwsClient := nws.NewNWSClient(
+		&http.Client{
+			Timeout: c.NWSClientTimeout,
+		},
+		c.NWSClientUserAgent,
+		c.NWSClientMaxRetries,
+		c.NWSClientRetryBackoff,
 	)
+
+	// Initialize cache backend based on configuration. A dragonfly outage, including at startup, only
+	// means results are generated without caching until it comes back
+	var dragonflyClient *dragonfly.DragonflyClient
+	var store cache.Store
+	switch strings.ToLower(c.CacheBackend) {
+	case "memory":
+		store = cache.NewMemoryStore(c.CacheMemoryMaxEntries)
+		slog.Info("using in-memory cache", slog.Int("max_entries", c.CacheMemoryMaxEntries))
+	case "dragonfly":
+		if c.DragonflyHost == "" {
+			slog.Error("DRAGONFLY_HOST is required for the dragonfly cache backend")
+			os.Exit(1)
+		}
+
+		dragonflyClient = dragonfly.NewDragonflyClient(
+			c.DragonflyHost,
+			c.DragonflyPort,
+			c.DragonflyAuth,
+		)
+
+		pingCtx, pingCancel := context.WithTimeout(ctx, time.Second*10)
+		if err := dragonflyClient.Ping(pingCtx); err != nil {
+			slog.Warn("dragonfly is unreachable, continuing without cache until it recovers", slog.String("error", err.Error()))
+		}
+		pingCancel()
+
+		store = cache.NewRedisStore(dragonflyClient.Client)
+		slog.Info("using dragonfly cache", slog.String("host", c.DragonflyHost))
+	default:
+		slog.Error("unknown cache backend", slog.String("backend", c.CacheBackend))
+		os.Exit(1)
+	}
+
+	sharedCache := cache.NewCache(store, c.CacheResultsDuration, c.CacheStaleDuration, c.DragonflyKeyPrefix)
+	archive := history.NewArchive(sharedCache, c.HistoryRetention)
+
+	resolver := locations.NewResolver(nwsClient, sharedCache, c.PointCacheDuration)
+
+	staticLocations := []locations.Location{{Name: "default", GridPoint: defaultGridPoint}}
+	if len(c.Locations) > 0 {
+		staticLocations = make([]locations.Location, 0, len(c.Locations))
+		for _, l := range c.Locations {
+			location, err := locations.ParseLocation(l)
+			if err != nil {
+				slog.Error("could not parse location", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			staticLocations = append(staticLocations, location)
+		}
+	}
+
+	registry, err := locations.NewRegistry(sharedCache, staticLocations, c.LocationsMaxDynamic)
 	if err != nil {
-		slog.Error("could not create dragonfly client", slog.String("error", err.Error()))
+		slog.Error("could not create location registry", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	llmHandler := handlers.NewLLMHandler(llmProvider, nwsClient, dragonflyClient, c.LLMHandlerTimeout)
+	llmHandler, err := handlers.NewLLMHandler(llmProvider, promptRegistry, nwsClient, sharedCache, archive, resolver, registry, c.LLMHandlerTimeout, c.LLMGenerationTimeout, defaultGridPoint, c.HourlyCacheDuration, c.AlertsCacheDuration, c.ConditionsCacheDuration)
+	if err != nil {
+		slog.Error("could not create llm handler", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// Start background worker if enabled
+	var forecastWorker *worker.ForecastWorker
 	if c.WorkerEnabled {
-		forecastWorker := worker.NewForecastWorker(
+		forecastWorker, err = worker.NewForecastWorker(
 			llmProvider,
+			promptRegistry,
 			nwsClient,
-			dragonflyClient,
-			c.WorkerInterval,
+			sharedCache,
+			archive,
+			registry,
+			c.WorkerPollInterval,
+			c.WorkerMaxStaleness,
 			c.WorkerTimeout,
-			c.GridPoint,
+			c.WorkerConcurrency,
 		)
-		go forecastWorker.Start(ctx)
+		if err != nil {
+			slog.Error("could not create forecast worker", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		if c.WorkerLeaderElection && dragonflyClient == nil {
+			slog.Warn("leader election requires the dragonfly cache backend, running the worker on this replica unconditionally")
+		}
+
+		if c.WorkerLeaderElection && dragonflyClient != nil {
+			elector, err := leader.NewElector(dragonflyClient, sharedCache, "worker", c.WorkerLeaseDuration)
+			if err != nil {
+				slog.Error("could not create leader elector", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			go elector.Run(ctx, forecastWorker.Start)
+		} else {
+			go forecastWorker.Start(ctx)
+		}
 	}
 
+	workerHandler := handlers.NewWorkerHandler(forecastWorker)
+
 	router := mux.NewRouter()
 	apiSubrouter := router.PathPrefix("/api").Subrouter()
 	v1Subrouter := apiSubrouter.PathPrefix("/v1").Subrouter()
 	forecastSubrouter := v1Subrouter.PathPrefix("/forecast").Subrouter()
 
+	v1Subrouter.HandleFunc("/points", llmHandler.GetPoint).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/alerts", llmHandler.GetAlerts).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/alerts/{office}/{x},{y}", llmHandler.GetAlerts).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/conditions", llmHandler.GetConditions).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/conditions/{office}/{x},{y}", llmHandler.GetConditions).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/worker/status", workerHandler.GetStatus).Methods(http.MethodGet)
+	v1Subrouter.HandleFunc("/locations", llmHandler.ListLocations).Methods(http.MethodGet)
+
 	forecastSubrouter.HandleFunc("/summary", llmHandler.GetForecastSummary).Methods(http.MethodGet)
 	forecastSubrouter.HandleFunc("/detailed", llmHandler.GetForcastPeriodsInformation).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/hourly", llmHandler.GetHourlyForecast).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/discussion", llmHandler.GetForecastDiscussion).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/history", llmHandler.GetForecastHistory).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/changes", llmHandler.GetForecastChanges).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/summary", llmHandler.GetForecastSummary).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/detailed", llmHandler.GetForcastPeriodsInformation).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/hourly", llmHandler.GetHourlyForecast).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/discussion", llmHandler.GetForecastDiscussion).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/history", llmHandler.GetForecastHistory).Methods(http.MethodGet)
+	forecastSubrouter.HandleFunc("/{office}/{x},{y}/changes", llmHandler.GetForecastChanges).Methods(http.MethodGet)
 
 	if c.AuthenticationEnabled {
 		authenticationMiddleware := middleware.NewAuthenticationMiddlewareClient(
 			middleware.WithAPIKeys(c.APIKeys),
 		)
 		apiSubrouter.Use(authenticationMiddleware.AuthenticationMiddleware)
+
+		// every location added at runtime costs LLM and NWS calls on each worker run, so only authenticated
+		// clients can change them
+		v1Subrouter.HandleFunc("/locations/{name}", llmHandler.PutLocation).Methods(http.MethodPut)
+		v1Subrouter.HandleFunc("/locations/{name}", llmHandler.DeleteLocation).Methods(http.MethodDelete)
+	} else {
+		slog.Info("authentication is disabled, locations can only be configured through LOCATIONS")
 	}
 
 	slog.Info("starting server", slog.String("port", "8080"))
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Entry wraps a cached value with its freshness. Entries are fresh until FreshUntil, after which they may
+// still be served while a refresh runs in the background until they expire from the cache entirely
+type Entry struct {
+	StoredAt   time.Time       `json:"stored_at"`
+	FreshUntil time.Time       `json:"fresh_until"`
+	Value      json.RawMessage `json:"value"`
+}
+
+// LastKnownGoodKey returns the key of the non-expiring copy of the last value successfully stored under key,
+// which is served when a fresh value cannot be generated
+func LastKnownGoodKey(key string) string {
+	return key + "-last-known-good"
+}
+
+// NewEntry creates an entry for value that is fresh for freshFor
+func NewEntry(value []byte, freshFor time.Duration) Entry {
+	now := time.Now()
+	return Entry{
+		StoredAt:   now,
+		FreshUntil: now.Add(freshFor),
+		Value:      value,
+	}
+}
+
+// ParseEntry decodes an entry read from the cache
+func ParseEntry(data []byte) (Entry, error) {
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, fmt.Errorf("could not unmarshal cache entry: %w", err)
+	}
+
+	if len(entry.Value) == 0 {
+		return Entry{}, fmt.Errorf("cache entry has no value")
+	}
+
+	return entry, nil
+}
+
+// Marshal encodes the entry for storing in the cache
+func (e Entry) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Stale reports whether the entry is past its freshness lifetime
+func (e Entry) Stale() bool {
+	return time.Now().After(e.FreshUntil)
+}
+
+// Age returns how long ago the entry was stored
+func (e Entry) Age() time.Duration {
+	return max(time.Since(e.StoredAt), 0)
+}
+
+// MaxAge returns the entry's freshness lifetime, measured from when it was stored like Age
+func (e Entry) MaxAge() time.Duration {
+	return max(e.FreshUntil.Sub(e.StoredAt), 0)
+}
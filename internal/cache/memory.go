@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryLogEntry struct {
+	t     time.Time
+	value []byte
+}
+
+type memoryLog struct {
+	retention time.Duration
+	entries   []memoryLogEntry
+}
+
+// prune drops the entries older than the log's retention
+func (l *memoryLog) prune(now time.Time) {
+	cutoff := now.Add(-l.retention)
+	l.entries = slices.DeleteFunc(l.entries, func(entry memoryLogEntry) bool {
+		return entry.t.Before(cutoff)
+	})
+}
+
+// MemoryStore is an in-process Store that evicts the least recently used keys once it holds MaxEntries.
+// Keys set without a TTL, such as last known good copies, and hashes, such as the location registry, are
+// never evicted and do not count towards MaxEntries. Logs are bounded by their retention rather than
+// MaxEntries. It is not shared between replicas, so it is meant for development, tests and single-replica
+// deployments
+type MemoryStore struct {
+	MaxEntries int
+
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	persistent map[string]*memoryItem
+	hashes     map[string]map[string][]byte
+	logs       map[string]*memoryLog
+}
+
+// NewMemoryStore creates a new in-memory store holding at most maxEntries keys
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+
+	return &MemoryStore{
+		MaxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		persistent: make(map[string]*memoryItem),
+		hashes:     make(map[string]map[string][]byte),
+		logs:       make(map[string]*memoryLog),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.get(key, time.Now())
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), item.value...), nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, ttl)
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		s.delete(key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetField(_ context.Context, key string, field string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.hashes[key][field]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MemoryStore) GetFields(_ context.Context, key string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := make(map[string][]byte, len(s.hashes[key]))
+	for field, value := range s.hashes[key] {
+		fields[field] = append([]byte(nil), value...)
+	}
+	return fields, nil
+}
+
+func (s *MemoryStore) SetField(_ context.Context, key string, field string, value []byte, maxFields int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, ok := s.hashes[key]
+	if !ok {
+		hash = make(map[string][]byte)
+		s.hashes[key] = hash
+	}
+
+	if _, ok := hash[field]; !ok && len(hash) >= maxFields {
+		return false, nil
+	}
+
+	hash[field] = append([]byte(nil), value...)
+	return true, nil
+}
+
+func (s *MemoryStore) DeleteField(_ context.Context, key string, field string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.hashes[key][field]; !ok {
+		return false, nil
+	}
+
+	delete(s.hashes[key], field)
+	if len(s.hashes[key]) == 0 {
+		delete(s.hashes, key)
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Lock(_ context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(key, time.Now()); ok {
+		return false, nil
+	}
+
+	s.set(key, []byte(owner), ttl)
+	return true, nil
+}
+
+func (s *MemoryStore) Unlock(_ context.Context, key string, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.get(key, time.Now()); ok && string(item.value) == owner {
+		s.delete(key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Append(_ context.Context, key string, t time.Time, value []byte, retention time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, ok := s.logs[key]
+	if !ok {
+		log = &memoryLog{}
+		s.logs[key] = log
+	}
+	log.retention = retention
+
+	i, _ := slices.BinarySearchFunc(log.entries, t, func(entry memoryLogEntry, t time.Time) int {
+		return entry.t.Compare(t)
+	})
+	log.entries = slices.Insert(log.entries, i, memoryLogEntry{t: t, value: append([]byte(nil), value...)})
+
+	// every log is pruned, so logs that are no longer appended to are dropped once their entries expire
+	now := time.Now()
+	for key, log := range s.logs {
+		log.prune(now)
+		if len(log.entries) == 0 {
+			delete(s.logs, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Range(_ context.Context, key string, from time.Time, to time.Time) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, ok := s.logs[key]
+	if !ok {
+		return nil, nil
+	}
+
+	var values [][]byte
+	for _, entry := range log.entries {
+		if entry.t.Before(from) || entry.t.After(to) {
+			continue
+		}
+		values = append(values, append([]byte(nil), entry.value...))
+	}
+	return values, nil
+}
+
+// get returns the unexpired item for key, marking it as recently used. s.mu must be held
+func (s *MemoryStore) get(key string, now time.Time) (*memoryItem, bool) {
+	if item, ok := s.persistent[key]; ok {
+		return item, true
+	}
+
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := element.Value.(*memoryItem)
+	if item.expired(now) {
+		s.remove(element)
+		return nil, false
+	}
+
+	s.order.MoveToFront(element)
+	return item, true
+}
+
+// set stores an item, evicting the least recently used expiring items over MaxEntries. Items without a
+// TTL are kept apart so they are never evicted. s.mu must be held
+func (s *MemoryStore) set(key string, value []byte, ttl time.Duration) {
+	item := &memoryItem{
+		key:   key,
+		value: append([]byte(nil), value...),
+	}
+
+	if ttl <= 0 {
+		if element, ok := s.items[key]; ok {
+			s.remove(element)
+		}
+		s.persistent[key] = item
+		return
+	}
+
+	item.expiresAt = time.Now().Add(ttl)
+	delete(s.persistent, key)
+
+	if element, ok := s.items[key]; ok {
+		element.Value = item
+		s.order.MoveToFront(element)
+	} else {
+		s.items[key] = s.order.PushFront(item)
+	}
+
+	for s.order.Len() > s.MaxEntries {
+		s.remove(s.order.Back())
+	}
+}
+
+// delete deletes the item for key, whether or not it expires. s.mu must be held
+func (s *MemoryStore) delete(key string) {
+	if element, ok := s.items[key]; ok {
+		s.remove(element)
+	}
+	delete(s.persistent, key)
+}
+
+// remove deletes an expiring item. s.mu must be held
+func (s *MemoryStore) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.items, element.Value.(*memoryItem).key)
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEviction(t *testing.T) {
+	type set struct {
+		key string
+		ttl time.Duration
+	}
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		sets       []set
+		// gets are done after every set, marking the keys as recently used
+		touch       []string
+		wantPresent []string
+		wantEvicted []string
+	}{
+		{
+			name:        "least recently set is evicted",
+			maxEntries:  2,
+			sets:        []set{{"a", time.Hour}, {"b", time.Hour}, {"c", time.Hour}},
+			wantPresent: []string{"b", "c"},
+			wantEvicted: []string{"a"},
+		},
+		{
+			name:        "get marks as recently used",
+			maxEntries:  2,
+			sets:        []set{{"a", time.Hour}, {"b", time.Hour}, {"c", time.Hour}},
+			touch:       []string{"a"},
+			wantPresent: []string{"a", "c"},
+			wantEvicted: []string{"b"},
+		},
+		{
+			name:        "non-expiring keys are never evicted",
+			maxEntries:  2,
+			sets:        []set{{"location", 0}, {"a", time.Hour}, {"b", time.Hour}, {"c", time.Hour}},
+			wantPresent: []string{"location", "b", "c"},
+			wantEvicted: []string{"a"},
+		},
+		{
+			name:        "non-expiring keys do not count towards max entries",
+			maxEntries:  1,
+			sets:        []set{{"x", 0}, {"y", 0}, {"z", 0}, {"a", time.Hour}},
+			wantPresent: []string{"x", "y", "z", "a"},
+		},
+		{
+			name:        "expiring key made non-expiring leaves the lru",
+			maxEntries:  1,
+			sets:        []set{{"a", time.Hour}, {"a", 0}, {"b", time.Hour}, {"c", time.Hour}},
+			wantPresent: []string{"a", "c"},
+			wantEvicted: []string{"b"},
+		},
+		{
+			name:        "non-expiring key made expiring can be evicted",
+			maxEntries:  1,
+			sets:        []set{{"a", 0}, {"a", time.Hour}, {"b", time.Hour}},
+			wantPresent: []string{"b"},
+			wantEvicted: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewMemoryStore(tt.maxEntries)
+
+			for _, set := range tt.sets {
+				if err := s.Set(ctx, set.key, []byte(set.key), set.ttl); err != nil {
+					t.Fatalf("Set(%q) error = %v", set.key, err)
+				}
+				for _, key := range tt.touch {
+					_, _ = s.Get(ctx, key)
+				}
+			}
+
+			for _, key := range tt.wantPresent {
+				value, err := s.Get(ctx, key)
+				if err != nil {
+					t.Errorf("Get(%q) error = %v, want present", key, err)
+				} else if string(value) != key {
+					t.Errorf("Get(%q) = %q, want %q", key, value, key)
+				}
+			}
+
+			for _, key := range tt.wantEvicted {
+				if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+					t.Errorf("Get(%q) error = %v, want ErrNotFound", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+
+	_ = s.Set(ctx, "expiring", []byte("value"), 10*time.Millisecond)
+	_ = s.Set(ctx, "persistent", []byte("value"), 0)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := s.Get(ctx, "expiring"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of an expired key error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(ctx, "persistent"); err != nil {
+		t.Errorf("Get() of a non-expiring key error = %v, want present", err)
+	}
+
+	_ = s.Delete(ctx, "persistent")
+	if _, err := s.Get(ctx, "persistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreFields(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(1)
+
+	tests := []struct {
+		field     string
+		value     string
+		maxFields int
+		wantSet   bool
+	}{
+		{field: "a", value: "1", maxFields: 2, wantSet: true},
+		{field: "b", value: "2", maxFields: 2, wantSet: true},
+		{field: "c", value: "3", maxFields: 2, wantSet: false},
+		{field: "a", value: "4", maxFields: 2, wantSet: true},
+		{field: "c", value: "3", maxFields: 3, wantSet: true},
+	}
+
+	for _, tt := range tests {
+		set, err := s.SetField(ctx, "hash", tt.field, []byte(tt.value), tt.maxFields)
+		if err != nil || set != tt.wantSet {
+			t.Errorf("SetField(%q, %q, %d) = %v, %v, want %v", tt.field, tt.value, tt.maxFields, set, err, tt.wantSet)
+		}
+	}
+
+	// hashes are not evicted by keys
+	_ = s.Set(ctx, "a", []byte("value"), time.Hour)
+	_ = s.Set(ctx, "b", []byte("value"), time.Hour)
+
+	fields, err := s.GetFields(ctx, "hash")
+	if err != nil {
+		t.Fatalf("GetFields() error = %v", err)
+	}
+	if len(fields) != 3 || string(fields["a"]) != "4" || string(fields["b"]) != "2" || string(fields["c"]) != "3" {
+		t.Errorf("GetFields() = %q, want a=4, b=2 and c=3", fields)
+	}
+
+	if deleted, _ := s.DeleteField(ctx, "hash", "a"); !deleted {
+		t.Errorf("DeleteField() of an existing field = false, want true")
+	}
+	if deleted, _ := s.DeleteField(ctx, "hash", "a"); deleted {
+		t.Errorf("DeleteField() of a deleted field = true, want false")
+	}
+	if _, err := s.GetField(ctx, "hash", "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetField() of a deleted field error = %v, want ErrNotFound", err)
+	}
+	if value, err := s.GetField(ctx, "hash", "b"); err != nil || string(value) != "2" {
+		t.Errorf("GetField() = %q, %v, want 2", value, err)
+	}
+}
+
+func TestMemoryStoreLogs(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	now := time.Now()
+
+	_ = s.Append(ctx, "old", now.Add(-2*time.Hour), []byte("old"), time.Hour)
+	_ = s.Append(ctx, "log", now.Add(-time.Minute), []byte("second"), time.Hour)
+	_ = s.Append(ctx, "log", now.Add(-2*time.Minute), []byte("first"), time.Hour)
+
+	values, err := s.Range(ctx, "log", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if len(values) != 2 || string(values[0]) != "first" || string(values[1]) != "second" {
+		t.Errorf("Range() = %q, want [first second]", values)
+	}
+
+	// appending to any log prunes the others, so logs nothing is appended to any more are dropped
+	if _, ok := s.logs["old"]; ok {
+		t.Errorf("log with only expired entries was not dropped")
+	}
+}
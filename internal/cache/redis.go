@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// unlockScript deletes the lock only if it is still held with the given value
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// setFieldScript sets a hash field only if it already exists or the hash has fewer than ARGV[3] fields
+var setFieldScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 or redis.call("HLEN", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore is a Store backed by Redis or Dragonfly
+type RedisStore struct {
+	Client *redis.Client
+}
+
+// NewRedisStore creates a new store using client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		Client: client,
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.Client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.Client.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) GetField(ctx context.Context, key string, field string) ([]byte, error) {
+	res, err := s.Client.HGet(ctx, key, field).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+func (s *RedisStore) GetFields(ctx context.Context, key string) (map[string][]byte, error) {
+	res, err := s.Client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]byte, len(res))
+	for field, value := range res {
+		fields[field] = []byte(value)
+	}
+	return fields, nil
+}
+
+func (s *RedisStore) SetField(ctx context.Context, key string, field string, value []byte, maxFields int) (bool, error) {
+	set, err := setFieldScript.Run(ctx, s.Client, []string{key}, field, value, maxFields).Int()
+	if err != nil {
+		return false, err
+	}
+	return set == 1, nil
+}
+
+func (s *RedisStore) DeleteField(ctx context.Context, key string, field string) (bool, error) {
+	deleted, err := s.Client.HDel(ctx, key, field).Result()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	return s.Client.SetNX(ctx, key, owner, ttl).Result()
+}
+
+func (s *RedisStore) Unlock(ctx context.Context, key string, owner string) error {
+	return unlockScript.Run(ctx, s.Client, []string{key}, owner).Err()
+}
+
+// Append keeps the log in a sorted set scored by time in milliseconds. The whole log expires once nothing
+// has been appended for retention
+func (s *RedisStore) Append(ctx context.Context, key string, t time.Time, value []byte, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+
+	pipe := s.Client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(t.UnixMilli()), Member: value})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", cutoff.UnixMilli()))
+	pipe.PExpire(ctx, key, retention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Range(ctx context.Context, key string, from time.Time, to time.Time) ([][]byte, error) {
+	res, err := s.Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMilli(), 10),
+		Max: strconv.FormatInt(to.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, 0, len(res))
+	for _, member := range res {
+		values = append(values, []byte(member))
+	}
+	return values, nil
+}
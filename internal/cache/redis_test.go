@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisStore(client)
+}
+
+func TestRedisStoreFields(t *testing.T) {
+	ctx := context.Background()
+	s := newTestRedisStore(t)
+
+	tests := []struct {
+		field     string
+		value     string
+		maxFields int
+		wantSet   bool
+	}{
+		{field: "a", value: "1", maxFields: 2, wantSet: true},
+		{field: "b", value: "2", maxFields: 2, wantSet: true},
+		{field: "c", value: "3", maxFields: 2, wantSet: false},
+		{field: "a", value: "4", maxFields: 2, wantSet: true},
+		{field: "c", value: "3", maxFields: 3, wantSet: true},
+	}
+
+	for _, tt := range tests {
+		set, err := s.SetField(ctx, "hash", tt.field, []byte(tt.value), tt.maxFields)
+		if err != nil || set != tt.wantSet {
+			t.Errorf("SetField(%q, %q, %d) = %v, %v, want %v", tt.field, tt.value, tt.maxFields, set, err, tt.wantSet)
+		}
+	}
+
+	fields, err := s.GetFields(ctx, "hash")
+	if err != nil {
+		t.Fatalf("GetFields() error = %v", err)
+	}
+	if len(fields) != 3 || string(fields["a"]) != "4" || string(fields["b"]) != "2" || string(fields["c"]) != "3" {
+		t.Errorf("GetFields() = %q, want a=4, b=2 and c=3", fields)
+	}
+
+	if deleted, _ := s.DeleteField(ctx, "hash", "a"); !deleted {
+		t.Errorf("DeleteField() of an existing field = false, want true")
+	}
+	if deleted, _ := s.DeleteField(ctx, "hash", "a"); deleted {
+		t.Errorf("DeleteField() of a deleted field = true, want false")
+	}
+	if _, err := s.GetField(ctx, "hash", "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetField() of a deleted field error = %v, want ErrNotFound", err)
+	}
+}
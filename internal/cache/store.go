@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("key not found")
+)
+
+// Store is a key-value cache backend
+type Store interface {
+	// Get returns the value stored under key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, expiring after ttl. A ttl of 0 stores it without expiry
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys, ignoring any that do not exist
+	Delete(ctx context.Context, keys ...string) error
+	// GetField returns the value of field in the hash under key, or ErrNotFound
+	GetField(ctx context.Context, key string, field string) ([]byte, error)
+	// GetFields returns every field and value in the hash under key
+	GetFields(ctx context.Context, key string) (map[string][]byte, error)
+	// SetField stores value under field in the hash under key, unless field is new and the hash already has
+	// maxFields fields, reporting whether it was stored. The check and the write are atomic and the hash
+	// does not expire
+	SetField(ctx context.Context, key string, field string, value []byte, maxFields int) (bool, error)
+	// DeleteField removes field from the hash under key, reporting whether it existed
+	DeleteField(ctx context.Context, key string, field string) (bool, error)
+	// Lock stores owner under key if the key does not exist, reporting whether the lock was acquired. The
+	// lock expires after ttl so a crashed owner cannot hold it forever
+	Lock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error)
+	// Unlock deletes the lock only if it is still held by owner
+	Unlock(ctx context.Context, key string, owner string) error
+	// Append adds value to the time-ordered log under key at t, dropping entries older than retention
+	Append(ctx context.Context, key string, t time.Time, value []byte, retention time.Duration) error
+	// Range returns the values appended to the log under key at times between from and to inclusive, oldest first
+	Range(ctx context.Context, key string, from time.Time, to time.Time) ([][]byte, error)
+}
+
+// Cache is a Store along with the key prefix and result durations shared by everything that caches
+type Cache struct {
+	Store
+	CacheResultsDuration time.Duration
+	CacheStaleDuration   time.Duration
+	KeyPrefix            string
+}
+
+// NewCache creates a new cache on top of store
+func NewCache(store Store, cacheResultsDuration time.Duration, cacheStaleDuration time.Duration, keyPrefix string) *Cache {
+	return &Cache{
+		Store:                store,
+		CacheResultsDuration: cacheResultsDuration,
+		CacheStaleDuration:   cacheStaleDuration,
+		KeyPrefix:            keyPrefix,
+	}
+}
+
+// Key builds a cache key from the configured key prefix and the given parts
+func (c *Cache) Key(parts ...string) string {
+	return strings.Join(append([]string{c.KeyPrefix}, parts...), "-")
+}
@@ -0,0 +1,202 @@
+package coalesce
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+// Coalescer serves cache entries with stale-while-revalidate and makes concurrent cache misses for the same
+// key share a single generation. Callers in the same process share one call, and across replicas a
+// "generation in progress" lock in the cache makes other replicas wait for the result to appear in the cache
+// instead of generating it again
+type Coalescer struct {
+	Cache        *cache.Cache
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	group singleflight.Group
+}
+
+// NewCoalescer creates a new coalescer. Generations, and waits for another replica's generation, are
+// bounded by timeout, which should be longer than callers are willing to wait so a slow generation can
+// still finish and be cached for later requests
+func NewCoalescer(c *cache.Cache, timeout time.Duration) *Coalescer {
+	return &Coalescer{
+		Cache:        c,
+		Timeout:      timeout,
+		PollInterval: 250 * time.Millisecond,
+	}
+}
+
+// Do returns the cache entry for key. Fresh entries are returned as-is and stale entries are returned
+// immediately while a refresh runs in the background. Otherwise generate is called and its result cached
+// as fresh for the cache's CacheResultsDuration, then kept for CacheStaleDuration after that.
+// The caller stops waiting when ctx is done, but a generation it started keeps running so other waiters
+// and later requests can still use the result
+func (c *Coalescer) Do(ctx context.Context, key string, generate func(ctx context.Context) ([]byte, error)) (cache.Entry, error) {
+	entry, err := c.get(ctx, key)
+	if err == nil && !entry.Stale() {
+		return entry, nil
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		// the flight outlives the caller that started it, whether it is waited on or a background refresh
+		flightCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.Timeout)
+		defer cancel()
+
+		return c.generateOnce(flightCtx, key, generate)
+	})
+
+	if err == nil {
+		slog.Debug("serving stale entry while refreshing", slog.String("key", key))
+		go func() {
+			if res := <-ch; res.Err != nil {
+				slog.Error("could not refresh stale entry", slog.String("error", res.Err.Error()), slog.String("key", key))
+			}
+		}()
+		return entry, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return cache.Entry{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return cache.Entry{}, res.Err
+		}
+		return res.Val.(cache.Entry), nil
+	}
+}
+
+// get returns the entry for key, or cache.ErrNotFound when there is no usable entry
+func (c *Coalescer) get(ctx context.Context, key string) (cache.Entry, error) {
+	res, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			slog.Error("could not get entry from cache", slog.String("error", err.Error()), slog.String("key", key))
+		}
+		return cache.Entry{}, cache.ErrNotFound
+	}
+
+	entry, err := cache.ParseEntry(res)
+	if err != nil {
+		slog.Error("could not parse entry from cache", slog.String("error", err.Error()), slog.String("key", key))
+		return cache.Entry{}, cache.ErrNotFound
+	}
+
+	return entry, nil
+}
+
+// Set caches value under key as a fresh entry, and keeps a non-expiring copy as the last known good value
+func (c *Coalescer) Set(ctx context.Context, key string, value []byte) (cache.Entry, error) {
+	entry := cache.NewEntry(value, c.Cache.CacheResultsDuration)
+
+	entryJSON, err := entry.Marshal()
+	if err != nil {
+		return cache.Entry{}, err
+	}
+
+	err = c.Cache.Set(ctx, key, entryJSON, c.Cache.CacheResultsDuration+c.Cache.CacheStaleDuration)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+
+	err = c.Cache.Set(ctx, cache.LastKnownGoodKey(key), entryJSON, 0)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+
+	return entry, nil
+}
+
+// LastKnownGood returns the last value successfully stored under key, regardless of its age, or
+// cache.ErrNotFound when there is none
+func (c *Coalescer) LastKnownGood(ctx context.Context, key string) (cache.Entry, error) {
+	return c.get(ctx, cache.LastKnownGoodKey(key))
+}
+
+// generateOnce generates the value under the cross-replica lock, or waits for the replica holding it
+func (c *Coalescer) generateOnce(ctx context.Context, key string, generate func(ctx context.Context) ([]byte, error)) (cache.Entry, error) {
+	lockKey := key + "-lock"
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return cache.Entry{}, fmt.Errorf("could not generate lock token: %w", err)
+	}
+	lockValue := hex.EncodeToString(token)
+
+	for {
+		// a previous flight or another replica may have refreshed the entry since the caller checked it
+		if entry, err := c.get(ctx, key); err == nil && !entry.Stale() {
+			return entry, nil
+		}
+
+		locked, err := c.Cache.Lock(ctx, lockKey, lockValue, c.Timeout)
+		if err != nil {
+			// without the lock we can still generate, just without cross-replica coalescing
+			slog.Error("could not acquire generation lock", slog.String("error", err.Error()), slog.String("key", key))
+			locked = true
+		}
+
+		if locked {
+			return c.generateLocked(ctx, key, lockKey, lockValue, generate)
+		}
+
+		slog.Debug("waiting for generation in progress", slog.String("key", key))
+		if err := c.waitForUnlock(ctx, key, lockKey); err != nil {
+			return cache.Entry{}, err
+		}
+	}
+}
+
+func (c *Coalescer) generateLocked(ctx context.Context, key string, lockKey string, lockValue string, generate func(ctx context.Context) ([]byte, error)) (cache.Entry, error) {
+	defer func() {
+		err := c.Cache.Unlock(context.WithoutCancel(ctx), lockKey, lockValue)
+		if err != nil {
+			slog.Error("could not release generation lock", slog.String("error", err.Error()), slog.String("key", key))
+		}
+	}()
+
+	value, err := generate(ctx)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+
+	entry, err := c.Set(ctx, key, value)
+	if err != nil {
+		slog.Error("could not set entry in cache", slog.String("error", err.Error()), slog.String("key", key))
+		return cache.NewEntry(value, c.Cache.CacheResultsDuration), nil
+	}
+
+	return entry, nil
+}
+
+// waitForUnlock polls until the lock is released. The holder caches the value before releasing it, so
+// the cache is then either filled or the holder failed and another attempt should take over
+func (c *Coalescer) waitForUnlock(ctx context.Context, key string, lockKey string) error {
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for generation of %s: %w", key, ctx.Err())
+		case <-ticker.C:
+		}
+
+		_, err := c.Cache.Get(ctx, lockKey)
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			slog.Error("could not check generation in progress", slog.String("error", err.Error()), slog.String("key", key))
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+)
+
+func newTestCoalescer(c *cache.Cache, timeout time.Duration) *Coalescer {
+	coalescer := NewCoalescer(c, timeout)
+	coalescer.PollInterval = 5 * time.Millisecond
+	return coalescer
+}
+
+func newTestCache() *cache.Cache {
+	return cache.NewCache(cache.NewMemoryStore(100), time.Hour, time.Hour, "test")
+}
+
+func TestDoCoalescesWithinReplica(t *testing.T) {
+	c := newTestCoalescer(newTestCache(), time.Second)
+
+	var generations atomic.Int32
+	release := make(chan struct{})
+	generate := func(ctx context.Context) ([]byte, error) {
+		generations.Add(1)
+		<-release
+		return []byte(`"forecast"`), nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	entries := make([]cache.Entry, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entries[i], errs[i] = c.Do(context.Background(), "key", generate)
+		}()
+	}
+
+	// give every caller time to join the flight before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := generations.Load(); n != 1 {
+		t.Errorf("generate called %d times, want 1", n)
+	}
+
+	for i := range callers {
+		if errs[i] != nil || string(entries[i].Value) != `"forecast"` {
+			t.Errorf("caller %d got %q, %v, want the shared generation", i, entries[i].Value, errs[i])
+		}
+	}
+}
+
+func TestDoWaitsForOtherReplica(t *testing.T) {
+	shared := newTestCache()
+	replicaA := newTestCoalescer(shared, time.Second)
+	replicaB := newTestCoalescer(shared, time.Second)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	doneA := make(chan error, 1)
+	go func() {
+		_, err := replicaA.Do(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+			close(started)
+			<-release
+			return []byte(`"from a"`), nil
+		})
+		doneA <- err
+	}()
+	<-started
+
+	var generationsB atomic.Int32
+	doneB := make(chan cache.Entry, 1)
+	go func() {
+		entry, err := replicaB.Do(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+			generationsB.Add(1)
+			return []byte(`"from b"`), nil
+		})
+		if err != nil {
+			t.Errorf("replica b Do() error = %v", err)
+		}
+		doneB <- entry
+	}()
+
+	// replica b is held off by the lock while replica a generates
+	select {
+	case <-doneB:
+		t.Fatal("replica b returned while replica a was still generating")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-doneA; err != nil {
+		t.Fatalf("replica a Do() error = %v", err)
+	}
+
+	select {
+	case entry := <-doneB:
+		if string(entry.Value) != `"from a"` {
+			t.Errorf("replica b got %q, want replica a's result", entry.Value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("replica b did not pick up replica a's result")
+	}
+
+	if n := generationsB.Load(); n != 0 {
+		t.Errorf("replica b generated %d times, want 0", n)
+	}
+}
+
+func TestDoOutlivesCaller(t *testing.T) {
+	tests := []struct {
+		name            string
+		timeout         time.Duration
+		generateFor     time.Duration
+		wantCached      bool
+		wantGenerateErr error
+	}{
+		{name: "finishes after the caller gives up", timeout: time.Second, generateFor: 50 * time.Millisecond, wantCached: true},
+		{name: "bounded by the generation timeout", timeout: 50 * time.Millisecond, generateFor: time.Second, wantCached: false, wantGenerateErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shared := newTestCache()
+			c := newTestCoalescer(shared, tt.timeout)
+
+			genDone := make(chan error, 1)
+			generate := func(ctx context.Context) ([]byte, error) {
+				select {
+				case <-time.After(tt.generateFor):
+					genDone <- nil
+					return []byte(`"forecast"`), nil
+				case <-ctx.Done():
+					genDone <- ctx.Err()
+					return nil, ctx.Err()
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			if _, err := c.Do(ctx, "key", generate); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Do() error = %v, want the caller's deadline", err)
+			}
+
+			select {
+			case err := <-genDone:
+				if !errors.Is(err, tt.wantGenerateErr) {
+					t.Errorf("generation ended with %v, want %v", err, tt.wantGenerateErr)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("generation did not end")
+			}
+
+			// the result is cached before the lock is released, so wait for the lock
+			deadline := time.Now().Add(time.Second)
+			for {
+				if _, err := shared.Get(context.Background(), "key-lock"); errors.Is(err, cache.ErrNotFound) || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			_, err := shared.Get(context.Background(), "key")
+			if cached := err == nil; cached != tt.wantCached {
+				t.Errorf("cached = %v, want %v", cached, tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestDoServesStaleWhileRevalidating(t *testing.T) {
+	shared := newTestCache()
+	c := newTestCoalescer(shared, time.Second)
+
+	stale := cache.Entry{
+		StoredAt:   time.Now().Add(-2 * time.Hour),
+		FreshUntil: time.Now().Add(-time.Hour),
+		Value:      []byte(`"stale"`),
+	}
+	staleJSON, _ := stale.Marshal()
+	if err := shared.Set(context.Background(), "key", staleJSON, time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	var generations atomic.Int32
+	release := make(chan struct{})
+	refreshed := make(chan struct{})
+	generate := func(ctx context.Context) ([]byte, error) {
+		generations.Add(1)
+		<-release
+		defer close(refreshed)
+		return []byte(`"fresh"`), nil
+	}
+
+	for i := range 5 {
+		entry, err := c.Do(context.Background(), "key", generate)
+		if err != nil {
+			t.Fatalf("Do() %d error = %v", i, err)
+		}
+		if string(entry.Value) != `"stale"` || !entry.Stale() || entry.Age() < 2*time.Hour {
+			t.Errorf("Do() %d = %q, stale %v, age %s, want the stale entry at least 2h old", i, entry.Value, entry.Stale(), entry.Age())
+		}
+	}
+
+	close(release)
+	<-refreshed
+
+	// the refresh is cached before its flight ends, after which the fresh entry is served
+	deadline := time.Now().Add(time.Second)
+	for {
+		entry, err := c.Do(context.Background(), "key", generate)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if !entry.Stale() {
+			if string(entry.Value) != `"fresh"` || entry.Age() > time.Minute {
+				t.Errorf("Do() after refresh = %q, age %s, want the fresh entry", entry.Value, entry.Age())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale entry was not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n := generations.Load(); n != 1 {
+		t.Errorf("generate called %d times, want a single background refresh", n)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -10,38 +11,84 @@ import (
 type Config struct {
 	LogLevel string `env:"LOG_LEVEL" envDefault:"error"`
 
-	// LLM Provider selection: "anthropic" or "openai"
-	LLMProvider string `env:"LLM_PROVIDER" envDefault:"anthropic"`
+	// LLM Provider selection: "anthropic" or "openai", or an ordered fallback chain such as "openai,anthropic"
+	LLMProviders []string `env:"LLM_PROVIDER" envSeparator:"," envDefault:"anthropic"`
+
+	// Circuit breaker for each provider in the chain, 0 failures disables it
+	LLMCircuitBreakerFailures int           `env:"LLM_CIRCUIT_BREAKER_FAILURES" envDefault:"3"`
+	LLMCircuitBreakerCooldown time.Duration `env:"LLM_CIRCUIT_BREAKER_COOLDOWN" envDefault:"1m"`
 
 	// Anthropic configuration
-	AnthropicAPIKey string `env:"ANTHROPIC_API_KEY"`
-	AnthropicModel  string `env:"ANTHROPIC_MODEL" envDefault:"claude-sonnet-4-5"`
+	AnthropicAPIKey  string        `env:"ANTHROPIC_API_KEY"`
+	AnthropicModel   string        `env:"ANTHROPIC_MODEL" envDefault:"claude-sonnet-4-5"`
+	AnthropicTimeout time.Duration `env:"ANTHROPIC_TIMEOUT"` // Optional: per-completion timeout before falling back
 
 	// OpenAI-compatible configuration
-	OpenAIAPIKey  string `env:"OPENAI_API_KEY"`
-	OpenAIModel   string `env:"OPENAI_MODEL" envDefault:"gpt-4o"`
-	OpenAIBaseURL string `env:"OPENAI_BASE_URL"`   // Optional: for OpenAI-compatible APIs (e.g., local LLMs, Azure)
-	OpenAINoThink bool   `env:"OPENAI_NO_THINK"`   // Optional: append /no_think to prompts (for Qwen 3 models)
+	OpenAIAPIKey  string        `env:"OPENAI_API_KEY"`
+	OpenAIModel   string        `env:"OPENAI_MODEL" envDefault:"gpt-4o"`
+	OpenAIBaseURL string        `env:"OPENAI_BASE_URL"` // Optional: for OpenAI-compatible APIs (e.g., local LLMs, Azure)
+	OpenAINoThink bool          `env:"OPENAI_NO_THINK"` // Optional: append /no_think to prompts (for Qwen 3 models)
+	OpenAITimeout time.Duration `env:"OPENAI_TIMEOUT"`  // Optional: per-completion timeout before falling back
+
+	// How JSON schemas are sent to OpenAI-compatible APIs: "json_schema", "grammar" (llama.cpp) or "none"
+	OpenAIStructuredOutput string `env:"OPENAI_STRUCTURED_OUTPUT" envDefault:"json_schema"`
+
+	// Directory of prompt templates overriding the embedded defaults, reloaded every PROMPTS_RELOAD_INTERVAL
+	// (0 disables reloading)
+	PromptsDir            string        `env:"PROMPTS_DIR"`
+	PromptsReloadInterval time.Duration `env:"PROMPTS_RELOAD_INTERVAL" envDefault:"1m"`
 
 	// Handler timeout (applies to all providers)
 	LLMHandlerTimeout time.Duration `env:"LLM_HANDLER_TIMEOUT" envDefault:"10s"`
 
-	// Background worker configuration
-	WorkerEnabled  bool          `env:"WORKER_ENABLED" envDefault:"true"`
-	WorkerInterval time.Duration `env:"WORKER_INTERVAL" envDefault:"30m"`
-	WorkerTimeout  time.Duration `env:"WORKER_TIMEOUT" envDefault:"60s"`
-	GridPoint      string        `env:"GRID_POINT" envDefault:"SEW/127,75"`
+	// Timeout for generating a forecast product, which keeps running after the request that started it gives up
+	LLMGenerationTimeout time.Duration `env:"LLM_GENERATION_TIMEOUT" envDefault:"60s"`
 
-	NWSClientTimeout time.Duration `env:"NWS_CLIENT_TIMEOUT" envDefault:"5s"`
+	// Background worker configuration
+	WorkerEnabled        bool          `env:"WORKER_ENABLED" envDefault:"true"`
+	WorkerPollInterval   time.Duration `env:"WORKER_POLL_INTERVAL" envDefault:"5m"`
+	WorkerMaxStaleness   time.Duration `env:"WORKER_MAX_STALENESS" envDefault:"3h"`
+	WorkerTimeout        time.Duration `env:"WORKER_TIMEOUT" envDefault:"60s"`
+	WorkerConcurrency    int           `env:"WORKER_CONCURRENCY" envDefault:"2"`
+	WorkerLeaderElection bool          `env:"WORKER_LEADER_ELECTION" envDefault:"true"`
+	WorkerLeaseDuration  time.Duration `env:"WORKER_LEASE_DURATION" envDefault:"30s"`
+	GridPoint            string        `env:"GRID_POINT" envDefault:"SEW/127,75"`
+
+	// Deprecated: WORKER_INTERVAL is the old name of WORKER_POLL_INTERVAL, used when that is not set
+	WorkerInterval time.Duration `env:"WORKER_INTERVAL"`
+
+	// Locations generated by the worker in addition to any added at runtime, e.g. home=SEW/127,75;cabin=OTX/54,120
+	// When empty, the default grid point is registered as "default"
+	Locations []string `env:"LOCATIONS" envSeparator:";"`
+
+	// Maximum number of locations that can be added at runtime through the API, which requires authentication
+	LocationsMaxDynamic int `env:"LOCATIONS_MAX_DYNAMIC" envDefault:"10"`
+
+	NWSClientTimeout      time.Duration `env:"NWS_CLIENT_TIMEOUT" envDefault:"5s"`
+	NWSClientUserAgent    string        `env:"NWS_CLIENT_USER_AGENT" envDefault:"lfpweather-forecast-inference-api (github.com/michaelpeterswa/lfpweather-forecast-inference-api)"`
+	NWSClientMaxRetries   int           `env:"NWS_CLIENT_MAX_RETRIES" envDefault:"2"`
+	NWSClientRetryBackoff time.Duration `env:"NWS_CLIENT_RETRY_BACKOFF" envDefault:"250ms"`
 
 	AuthenticationEnabled bool     `env:"AUTHENTICATION_ENABLED" envDefault:"false"`
 	APIKeys               []string `env:"API_KEYS" envSeparator:","`
 
-	DragonflyHost        string        `env:"DRAGONFLY_HOST,required"`
-	DragonflyPort        int           `env:"DRAGONFLY_PORT" envDefault:"6379"`
-	DragonflyAuth        string        `env:"DRAGONFLY_AUTH"`
-	DragonflyKeyPrefix   string        `env:"DRAGONFLY_KEY_PREFIX" envDefault:"lfia"`
-	CacheResultsDuration time.Duration `env:"CACHE_RESULTS_DURATION" envDefault:"6h"`
+	// Cache backend selection: "dragonfly" or "memory"
+	CacheBackend          string `env:"CACHE_BACKEND" envDefault:"dragonfly"`
+	CacheMemoryMaxEntries int    `env:"CACHE_MEMORY_MAX_ENTRIES" envDefault:"10000"`
+
+	DragonflyHost           string        `env:"DRAGONFLY_HOST"`
+	DragonflyPort           int           `env:"DRAGONFLY_PORT" envDefault:"6379"`
+	DragonflyAuth           string        `env:"DRAGONFLY_AUTH"`
+	DragonflyKeyPrefix      string        `env:"DRAGONFLY_KEY_PREFIX" envDefault:"lfia"`
+	CacheResultsDuration    time.Duration `env:"CACHE_RESULTS_DURATION" envDefault:"6h"`
+	CacheStaleDuration      time.Duration `env:"CACHE_STALE_DURATION" envDefault:"24h"`
+	PointCacheDuration      time.Duration `env:"POINT_CACHE_DURATION" envDefault:"720h"`
+	HourlyCacheDuration     time.Duration `env:"HOURLY_CACHE_DURATION" envDefault:"1h"`
+	AlertsCacheDuration     time.Duration `env:"ALERTS_CACHE_DURATION" envDefault:"5m"`
+	ConditionsCacheDuration time.Duration `env:"CONDITIONS_CACHE_DURATION" envDefault:"10m"`
+
+	// How long generated forecast products are kept in the history archive
+	HistoryRetention time.Duration `env:"HISTORY_RETENTION" envDefault:"720h"`
 
 	MetricsEnabled bool `env:"METRICS_ENABLED" envDefault:"true"`
 	MetricsPort    int  `env:"METRICS_PORT" envDefault:"8081"`
@@ -60,5 +107,9 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if _, ok := os.LookupEnv("WORKER_POLL_INTERVAL"); !ok && cfg.WorkerInterval != 0 {
+		cfg.WorkerPollInterval = cfg.WorkerInterval
+	}
+
 	return &cfg, nil
 }
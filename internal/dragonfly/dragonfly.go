@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -15,12 +14,11 @@ var (
 
 type DragonflyClient struct {
 	Client *redis.Client
-	//TODO: consider turning into map
-	CacheResultsDuration time.Duration
-	KeyPrefix            string
 }
 
-func NewDragonflyClient(host string, port int, password string, cacheResultsDuration time.Duration, keyPrefix string) (*DragonflyClient, error) {
+// NewDragonflyClient creates a new dragonfly client. Connections are made lazily, so the client can be
+// created while dragonfly is unreachable and starts working once it comes back
+func NewDragonflyClient(host string, port int, password string) *DragonflyClient {
 	redisOpts := &redis.Options{
 		Addr: fmt.Sprintf("%s:%d", host, port),
 		DB:   0,
@@ -30,23 +28,19 @@ func NewDragonflyClient(host string, port int, password string, cacheResultsDura
 		redisOpts.Password = password
 	}
 
-	redisClient := redis.NewClient(redisOpts)
-
-	pingCtx, pingCancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer pingCancel()
-
-	_, err := redisClient.Ping(pingCtx).Result()
-	if err != nil {
-		return nil, err
-	}
-
 	return &DragonflyClient{
-		Client:               redisClient,
-		CacheResultsDuration: cacheResultsDuration,
-		KeyPrefix:            keyPrefix,
-	}, nil
+		Client: redis.NewClient(redisOpts),
+	}
 }
 
 func (dc *DragonflyClient) GetClient() *redis.Client {
 	return dc.Client
 }
+
+// Ping checks that dragonfly is reachable
+func (dc *DragonflyClient) Ping(ctx context.Context) error {
+	if err := dc.Client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnableToPingDragonfly, err)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+// Thresholds above which a change to a period is considered material
+const (
+	materialTemperatureDelta   = 3
+	materialPrecipitationDelta = 20
+	precipitationThreshold     = 30
+	materialWindSpeedDelta     = 10
+	materialWindGustDelta      = 10
+)
+
+// PeriodChange is the difference between a previous and the current forecast for a single period
+type PeriodChange struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	PreviousTemperature int `json:"previous_temperature"`
+	Temperature         int `json:"temperature"`
+	TemperatureDelta    int `json:"temperature_delta"`
+
+	PreviousProbabilityOfPrecipitation int      `json:"previous_probability_of_precipitation"`
+	ProbabilityOfPrecipitation         int      `json:"probability_of_precipitation"`
+	PreviousPrecipitationAmount        *float64 `json:"previous_precipitation_amount"`
+	PrecipitationAmount                *float64 `json:"precipitation_amount"`
+	NewPrecipitation                   bool     `json:"new_precipitation"`
+	PrecipitationRemoved               bool     `json:"precipitation_removed"`
+
+	PreviousWindSpeed     string   `json:"previous_wind_speed"`
+	WindSpeed             string   `json:"wind_speed"`
+	WindSpeedDelta        int      `json:"wind_speed_delta"`
+	PreviousWindGust      *float64 `json:"previous_wind_gust"`
+	WindGust              *float64 `json:"wind_gust"`
+	PreviousWindDirection string   `json:"previous_wind_direction"`
+	WindDirection         string   `json:"wind_direction"`
+
+	PreviousShortForecast string `json:"previous_short_forecast"`
+	ShortForecast         string `json:"short_forecast"`
+
+	// Material is true when the change is large enough to be worth telling users about
+	Material bool `json:"material"`
+}
+
+type ForecastChangesResponse struct {
+	PreviousGeneratedAt        time.Time      `json:"previous_generated_at"`
+	PreviousForecastUpdateTime time.Time      `json:"previous_forecast_update_time"`
+	ForecastUpdateTime         time.Time      `json:"forecast_update_time"`
+	Material                   bool           `json:"material"`
+	Explanation                string         `json:"explanation"`
+	Provider                   string         `json:"provider,omitempty"`
+	PromptVersion              string         `json:"prompt_version,omitempty"`
+	Changes                    []PeriodChange `json:"changes"`
+	LastUpdated                time.Time      `json:"last_updated"`
+}
+
+// ForecastChangesExplanation is an explanation of material forecast changes and the provider that wrote it
+type ForecastChangesExplanation struct {
+	Explanation string `json:"explanation"`
+	Provider    string `json:"provider"`
+}
+
+// Simplified converts a detailed period back into the NWS period it was generated from, without the
+// gridpoint data that is not part of the detailed forecast
+func (p JoinedForecastPeriodsInformation) Simplified() nws.SimplifiedForecastPeriods {
+	return nws.SimplifiedForecastPeriods{
+		DetailedForecast:           p.DetailedForecast,
+		ShortForecast:              p.ShortForecast,
+		StartTime:                  p.StartTime,
+		EndTime:                    p.EndTime,
+		Temperature:                p.Temperature,
+		ProbabilityOfPrecipitation: p.ProbabilityOfPrecipitation,
+		WindSpeed:                  p.WindSpeed,
+		WindDirection:              p.WindDirection,
+		Name:                       p.Name,
+		IsDaytime:                  p.TimeOfDay == "day",
+		PrecipitationAmount:        p.PrecipitationAmount,
+		SnowfallAmount:             p.SnowfallAmount,
+		WindGust:                   p.WindGust,
+	}
+}
+
+// DiffForecastPeriods compares the periods of two forecasts by their time windows, returning a change for
+// every period present in both that differs. Periods that have since passed or were not yet forecast are
+// skipped
+func DiffForecastPeriods(previous []nws.SimplifiedForecastPeriods, current []nws.SimplifiedForecastPeriods) []PeriodChange {
+	changes := make([]PeriodChange, 0)
+	for _, period := range current {
+		previousPeriod, ok := matchPeriod(previous, period)
+		if !ok || !periodChanged(previousPeriod, period) {
+			continue
+		}
+
+		changes = append(changes, diffForecastPeriod(previousPeriod, period))
+	}
+
+	return changes
+}
+
+// matchPeriod returns the previous period overlapping current the most. The first period of a forecast
+// starts when it was updated, e.g. "This Afternoon" replacing "Today", so start times do not line up
+func matchPeriod(previous []nws.SimplifiedForecastPeriods, current nws.SimplifiedForecastPeriods) (nws.SimplifiedForecastPeriods, bool) {
+	var match nws.SimplifiedForecastPeriods
+	var longest time.Duration
+	for _, period := range previous {
+		if period.IsDaytime != current.IsDaytime {
+			continue
+		}
+
+		start := max(period.StartTime.UnixNano(), current.StartTime.UnixNano())
+		end := min(period.EndTime.UnixNano(), current.EndTime.UnixNano())
+		if overlap := time.Duration(end - start); overlap > longest {
+			match, longest = period, overlap
+		}
+	}
+
+	return match, longest > 0
+}
+
+// periodChanged reports whether any of the compared fields of a period differ
+func periodChanged(previous nws.SimplifiedForecastPeriods, current nws.SimplifiedForecastPeriods) bool {
+	return previous.Temperature != current.Temperature ||
+		previous.ProbabilityOfPrecipitation != current.ProbabilityOfPrecipitation ||
+		!sameAmount(previous.PrecipitationAmount, current.PrecipitationAmount) ||
+		previous.WindSpeed != current.WindSpeed ||
+		!sameAmount(previous.WindGust, current.WindGust) ||
+		previous.WindDirection != current.WindDirection ||
+		previous.ShortForecast != current.ShortForecast
+}
+
+func diffForecastPeriod(previous nws.SimplifiedForecastPeriods, current nws.SimplifiedForecastPeriods) PeriodChange {
+	previousSpeed, _ := nws.MaxWindSpeed(previous.WindSpeed)
+	currentSpeed, _ := nws.MaxWindSpeed(current.WindSpeed)
+
+	change := PeriodChange{
+		Name:      current.Name,
+		StartTime: current.StartTime,
+		EndTime:   current.EndTime,
+
+		PreviousTemperature: previous.Temperature,
+		Temperature:         current.Temperature,
+		TemperatureDelta:    current.Temperature - previous.Temperature,
+
+		PreviousProbabilityOfPrecipitation: previous.ProbabilityOfPrecipitation,
+		ProbabilityOfPrecipitation:         current.ProbabilityOfPrecipitation,
+		PreviousPrecipitationAmount:        previous.PrecipitationAmount,
+		PrecipitationAmount:                current.PrecipitationAmount,
+		NewPrecipitation:                   !wet(previous) && wet(current),
+		PrecipitationRemoved:               wet(previous) && !wet(current),
+
+		PreviousWindSpeed:     previous.WindSpeed,
+		WindSpeed:             current.WindSpeed,
+		WindSpeedDelta:        currentSpeed - previousSpeed,
+		PreviousWindGust:      previous.WindGust,
+		WindGust:              current.WindGust,
+		PreviousWindDirection: previous.WindDirection,
+		WindDirection:         current.WindDirection,
+
+		PreviousShortForecast: previous.ShortForecast,
+		ShortForecast:         current.ShortForecast,
+	}
+
+	change.Material = abs(change.TemperatureDelta) >= materialTemperatureDelta ||
+		change.NewPrecipitation ||
+		change.PrecipitationRemoved ||
+		abs(change.ProbabilityOfPrecipitation-change.PreviousProbabilityOfPrecipitation) >= materialPrecipitationDelta ||
+		abs(change.WindSpeedDelta) >= materialWindSpeedDelta ||
+		math.Abs(amountDelta(change.PreviousWindGust, change.WindGust)) >= materialWindGustDelta
+
+	return change
+}
+
+// wet reports whether precipitation is expected during a period
+func wet(period nws.SimplifiedForecastPeriods) bool {
+	return period.ProbabilityOfPrecipitation >= precipitationThreshold || valueOrZero(period.PrecipitationAmount) >= 0.01
+}
+
+// sameAmount compares gridpoint amounts. Amounts are missing when the gridpoint data was unavailable, so
+// they are only compared when known for both forecasts
+func sameAmount(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	return *a == *b
+}
+
+// amountDelta returns the difference between two gridpoint amounts, or 0 when either is missing
+func amountDelta(previous *float64, current *float64) float64 {
+	if previous == nil || current == nil {
+		return 0
+	}
+	return *current - *previous
+}
+
+func valueOrZero(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// MaterialChanges returns the changes worth telling users about
+func MaterialChanges(changes []PeriodChange) []PeriodChange {
+	material := make([]PeriodChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Material {
+			material = append(material, change)
+		}
+	}
+	return material
+}
+
+// CompareForecast diffs the current forecast for a grid point against previous periods. It does not call
+// the LLM, see ExplainForecastChanges
+func (g *Generator) CompareForecast(ctx context.Context, gridPoint nws.GridPoint, previous []nws.SimplifiedForecastPeriods) (ForecastChangesResponse, error) {
+	forecast, err := g.NWSClient.GetForecast(ctx, gridPoint.String())
+	if err != nil {
+		return ForecastChangesResponse{}, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	periods := nws.SimplifyForecastNPeriods(forecast, -1)
+	g.addQuantitativeForecast(ctx, gridPoint, periods)
+
+	changes := DiffForecastPeriods(previous, periods)
+	return ForecastChangesResponse{
+		ForecastUpdateTime: forecast.Properties.UpdateTime,
+		Material:           len(MaterialChanges(changes)) > 0,
+		Changes:            changes,
+		LastUpdated:        time.Now(),
+	}, nil
+}
+
+// ExplainForecastChanges writes a short explanation of material forecast changes
+func (g *Generator) ExplainForecastChanges(ctx context.Context, changes []PeriodChange) (ForecastChangesExplanation, error) {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return ForecastChangesExplanation{}, fmt.Errorf("failed to marshal forecast changes: %w", err)
+	}
+
+	prompt, err := g.Prompts.Render(prompts.ForecastChanges, prompts.Data{
+		Input: string(changesJSON),
+	})
+	if err != nil {
+		return ForecastChangesExplanation{}, err
+	}
+
+	response, err := g.LLMProvider.Complete(ctx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    1024,
+	})
+	if err != nil {
+		return ForecastChangesExplanation{}, fmt.Errorf("failed to get forecast changes explanation: %w", err)
+	}
+
+	return ForecastChangesExplanation{
+		Explanation: strings.TrimSpace(response.Content),
+		Provider:    response.Provider,
+	}, nil
+}
@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+func period(name string, start time.Time, hours int, isDaytime bool) nws.SimplifiedForecastPeriods {
+	return nws.SimplifiedForecastPeriods{
+		Name:      name,
+		StartTime: start,
+		EndTime:   start.Add(time.Duration(hours) * time.Hour),
+		IsDaytime: isDaytime,
+	}
+}
+
+func TestMatchPeriod(t *testing.T) {
+	sixAM := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	previous := []nws.SimplifiedForecastPeriods{
+		period("Today", sixAM, 12, true),
+		period("Tonight", sixAM.Add(12*time.Hour), 12, false),
+		period("Wednesday", sixAM.Add(24*time.Hour), 12, true),
+	}
+
+	tests := []struct {
+		name      string
+		current   nws.SimplifiedForecastPeriods
+		wantName  string
+		wantMatch bool
+	}{
+		{
+			name:      "same boundaries",
+			current:   period("Today", sixAM, 12, true),
+			wantName:  "Today",
+			wantMatch: true,
+		},
+		{
+			name:      "shifted start",
+			current:   period("This Afternoon", sixAM.Add(7*time.Hour), 5, true),
+			wantName:  "Today",
+			wantMatch: true,
+		},
+		{
+			name:      "overlaps two periods, the longer overlap wins",
+			current:   period("Wednesday", sixAM.Add(20*time.Hour), 12, true),
+			wantName:  "Wednesday",
+			wantMatch: true,
+		},
+		{
+			name:      "night only matches night",
+			current:   period("Tonight", sixAM.Add(11*time.Hour), 12, false),
+			wantName:  "Tonight",
+			wantMatch: true,
+		},
+		{
+			name:    "day does not match an overlapping night",
+			current: period("Today", sixAM.Add(12*time.Hour), 2, true),
+		},
+		{
+			name:    "adjacent periods do not overlap",
+			current: period("Wednesday Night", sixAM.Add(36*time.Hour), 12, true),
+		},
+		{
+			name:    "not yet forecast",
+			current: period("Thursday", sixAM.Add(48*time.Hour), 12, true),
+		},
+		{
+			name:    "already passed",
+			current: period("Monday", sixAM.Add(-24*time.Hour), 12, true),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchPeriod(previous, tt.current)
+			if ok != tt.wantMatch {
+				t.Fatalf("matchPeriod() ok = %v, want %v", ok, tt.wantMatch)
+			}
+			if ok && got.Name != tt.wantName {
+				t.Errorf("matchPeriod() = %q, want %q", got.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDiffForecastPeriodMaterial(t *testing.T) {
+	amount := func(v float64) *float64 {
+		return &v
+	}
+	base := nws.SimplifiedForecastPeriods{
+		Name:                       "Today",
+		Temperature:                50,
+		ProbabilityOfPrecipitation: 10,
+		PrecipitationAmount:        amount(0),
+		WindSpeed:                  "5 mph",
+		WindGust:                   amount(10),
+		WindDirection:              "S",
+		ShortForecast:              "Cloudy",
+		IsDaytime:                  true,
+	}
+
+	tests := []struct {
+		name         string
+		previous     func(p *nws.SimplifiedForecastPeriods)
+		current      func(p *nws.SimplifiedForecastPeriods)
+		wantMaterial bool
+	}{
+		{
+			name:    "temperature just under the threshold",
+			current: func(p *nws.SimplifiedForecastPeriods) { p.Temperature = 52 },
+		},
+		{
+			name:         "temperature rise at the threshold",
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.Temperature = 53 },
+			wantMaterial: true,
+		},
+		{
+			name:         "temperature drop at the threshold",
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.Temperature = 47 },
+			wantMaterial: true,
+		},
+		{
+			name:    "chance of precipitation just under the threshold",
+			current: func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 29 },
+		},
+		{
+			name:         "chance of precipitation at the threshold",
+			previous:     func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 60 },
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 80 },
+			wantMaterial: true,
+		},
+		{
+			name:         "chance of precipitation becoming wet",
+			previous:     func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 15 },
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 30 },
+			wantMaterial: true,
+		},
+		{
+			name:         "chance of precipitation becoming dry",
+			previous:     func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 30 },
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.ProbabilityOfPrecipitation = 15 },
+			wantMaterial: true,
+		},
+		{
+			name:    "precipitation amount just under a trace",
+			current: func(p *nws.SimplifiedForecastPeriods) { p.PrecipitationAmount = amount(0.005) },
+		},
+		{
+			name:         "precipitation amount at a trace",
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.PrecipitationAmount = amount(0.01) },
+			wantMaterial: true,
+		},
+		{
+			name:    "wind speed just under the threshold",
+			current: func(p *nws.SimplifiedForecastPeriods) { p.WindSpeed = "5 to 14 mph" },
+		},
+		{
+			name:         "wind speed at the threshold",
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.WindSpeed = "5 to 15 mph" },
+			wantMaterial: true,
+		},
+		{
+			name:    "wind gust just under the threshold",
+			current: func(p *nws.SimplifiedForecastPeriods) { p.WindGust = amount(19.9) },
+		},
+		{
+			name:         "wind gust at the threshold",
+			current:      func(p *nws.SimplifiedForecastPeriods) { p.WindGust = amount(20) },
+			wantMaterial: true,
+		},
+		{
+			name:     "wind gust previously unknown",
+			previous: func(p *nws.SimplifiedForecastPeriods) { p.WindGust = nil },
+			current: func(p *nws.SimplifiedForecastPeriods) {
+				p.ShortForecast = "Breezy"
+				p.WindGust = amount(40)
+			},
+		},
+		{
+			name: "wording and direction only",
+			current: func(p *nws.SimplifiedForecastPeriods) {
+				p.ShortForecast = "Mostly Cloudy"
+				p.WindDirection = "SW"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, current := base, base
+			if tt.previous != nil {
+				tt.previous(&previous)
+			}
+			if tt.current != nil {
+				tt.current(&current)
+			}
+
+			if !periodChanged(previous, current) {
+				t.Fatalf("periodChanged() = false, the test case does not change the period")
+			}
+			if got := diffForecastPeriod(previous, current).Material; got != tt.wantMaterial {
+				t.Errorf("diffForecastPeriod() material = %v, want %v", got, tt.wantMaterial)
+			}
+		})
+	}
+}
+
+func TestDiffForecastPeriods(t *testing.T) {
+	sixAM := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	withTemperature := func(p nws.SimplifiedForecastPeriods, temperature int) nws.SimplifiedForecastPeriods {
+		p.Temperature = temperature
+		return p
+	}
+
+	previous := []nws.SimplifiedForecastPeriods{
+		withTemperature(period("Today", sixAM, 12, true), 60),
+		withTemperature(period("Tonight", sixAM.Add(12*time.Hour), 12, false), 45),
+	}
+	current := []nws.SimplifiedForecastPeriods{
+		withTemperature(period("This Afternoon", sixAM.Add(6*time.Hour), 6, true), 65),
+		withTemperature(period("Tonight", sixAM.Add(12*time.Hour), 12, false), 45),
+		withTemperature(period("Wednesday", sixAM.Add(24*time.Hour), 12, true), 70),
+	}
+
+	changes := DiffForecastPeriods(previous, current)
+	if len(changes) != 1 {
+		t.Fatalf("DiffForecastPeriods() = %d changes, want 1: %+v", len(changes), changes)
+	}
+	if changes[0].Name != "This Afternoon" || changes[0].PreviousTemperature != 60 || changes[0].TemperatureDelta != 5 {
+		t.Errorf("DiffForecastPeriods() = %+v, want This Afternoon from 60 by 5", changes[0])
+	}
+	if len(MaterialChanges(changes)) != 1 {
+		t.Errorf("MaterialChanges() = %d changes, want 1", len(MaterialChanges(changes)))
+	}
+}
+
+func TestExplainForecastChanges(t *testing.T) {
+	registry, err := prompts.NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	provider := &scriptedProvider{responses: []string{"  Rain is now expected tonight.\n"}}
+	g := &Generator{LLMProvider: provider, Prompts: registry}
+
+	got, err := g.ExplainForecastChanges(context.Background(), []PeriodChange{{Name: "Tonight", NewPrecipitation: true, Material: true}})
+	if err != nil {
+		t.Fatalf("ExplainForecastChanges() error = %v", err)
+	}
+
+	want := ForecastChangesExplanation{Explanation: "Rain is now expected tonight.", Provider: "scripted"}
+	if got != want {
+		t.Errorf("ExplainForecastChanges() = %+v, want %+v", got, want)
+	}
+}
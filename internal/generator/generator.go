@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+
+// Generator turns NWS forecasts into LLM-written forecast products. It is shared by the
+// HTTP handlers and the background worker so both produce identical results
+type Generator struct {
+	LLMProvider llm.Provider
+	Prompts     *prompts.Registry
+	NWSClient   *nws.NWSClient
+
+	iconFallbacksCounter   metric.Int64Counter
+	iconComparisonsCounter metric.Int64Counter
+}
+
+// NewGenerator creates a new forecast generator
+func NewGenerator(provider llm.Provider, promptRegistry *prompts.Registry, nwsClient *nws.NWSClient) (*Generator, error) {
+	iconFallbacksCounter, err := otel.Meter(meterName).Int64Counter(
+		"icons.fallbacks",
+		metric.WithDescription("number of icons chosen by rules because the llm failed or chose an invalid icon, by product"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create icon fallbacks counter: %w", err)
+	}
+
+	iconComparisonsCounter, err := otel.Meter(meterName).Int64Counter(
+		"icons.comparisons",
+		metric.WithDescription("number of llm-chosen icons compared against the rule-based icon, by product and result"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create icon comparisons counter: %w", err)
+	}
+
+	return &Generator{
+		LLMProvider:            provider,
+		Prompts:                promptRegistry,
+		NWSClient:              nwsClient,
+		iconFallbacksCounter:   iconFallbacksCounter,
+		iconComparisonsCounter: iconComparisonsCounter,
+	}, nil
+}
+
+// addQuantitativeForecast adds precipitation amounts and other raw gridpoint data to the periods. The
+// text forecast is still usable without them, so failures are only logged
+func (g *Generator) addQuantitativeForecast(ctx context.Context, gridPoint nws.GridPoint, periods []nws.SimplifiedForecastPeriods) {
+	data, err := g.NWSClient.GetGridpointData(ctx, gridPoint.String())
+	if err != nil {
+		slog.Error("failed to get gridpoint data", slog.String("error", err.Error()), slog.String("grid_point", gridPoint.String()))
+		return
+	}
+
+	nws.AddQuantitativeForecast(periods, data)
+}
+
+func StripMarkdownCodeBlock(text string) string {
+	// Remove ```json or ``` prefix and ``` suffix
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```json") {
+		text = strings.TrimPrefix(text, "```json")
+	} else if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+	}
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
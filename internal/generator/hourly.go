@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+type HourlyForecastPeriodInformation struct {
+	Number int    `json:"number"`
+	Icon   string `json:"icon"`
+}
+
+type HourlyForecastInformation struct {
+	Nowcast string                            `json:"nowcast"`
+	Periods []HourlyForecastPeriodInformation `json:"periods"`
+}
+
+type JoinedHourlyForecastPeriod struct {
+	Number                     int       `json:"number"`
+	TimeOfDay                  string    `json:"time_of_day"`
+	Icon                       string    `json:"icon"`
+	Beaufort                   string    `json:"beaufort"`
+	BeaufortForce              *int      `json:"beaufort_force"`
+	ShortForecast              string    `json:"short_forecast"`
+	StartTime                  time.Time `json:"start_time"`
+	EndTime                    time.Time `json:"end_time"`
+	Temperature                int       `json:"temperature"`
+	ProbabilityOfPrecipitation int       `json:"probability_of_precipitation"`
+	RelativeHumidity           int       `json:"relative_humidity"`
+	Dewpoint                   int       `json:"dewpoint"`
+	WindSpeed                  string    `json:"wind_speed"`
+	WindDirection              string    `json:"wind_direction"`
+}
+
+func JoinHourlyForecastPeriod(hfpi HourlyForecastPeriodInformation, period nws.SimplifiedHourlyForecastPeriod) JoinedHourlyForecastPeriod {
+	var beaufortForce *int
+	force, beaufort, ok := nws.Beaufort(period.WindSpeed)
+	if ok {
+		beaufortForce = &force
+	}
+
+	return JoinedHourlyForecastPeriod{
+		Number:                     period.Number,
+		TimeOfDay:                  TimeOfDay(period.IsDaytime),
+		Icon:                       hfpi.Icon,
+		Beaufort:                   beaufort,
+		BeaufortForce:              beaufortForce,
+		ShortForecast:              period.ShortForecast,
+		StartTime:                  period.StartTime,
+		EndTime:                    period.EndTime,
+		Temperature:                period.Temperature,
+		ProbabilityOfPrecipitation: period.ProbabilityOfPrecipitation,
+		RelativeHumidity:           period.RelativeHumidity,
+		Dewpoint:                   period.Dewpoint,
+		WindSpeed:                  period.WindSpeed,
+		WindDirection:              period.WindDirection,
+	}
+}
+
+type GetHourlyForecastResponse struct {
+	Nowcast       string                       `json:"nowcast"`
+	Periods       []JoinedHourlyForecastPeriod `json:"periods"`
+	LastUpdated   time.Time                    `json:"last_updated"`
+	Provider      string                       `json:"provider"`
+	PromptVersion string                       `json:"prompt_version"`
+}
+
+// GenerateHourlyForecast generates a nowcast and an icon for each of the next hours for a grid point. When
+// the LLM fails, or its output is still incomplete after a repair, the missing parts are filled in from the
+// NWS forecast
+func (g *Generator) GenerateHourlyForecast(ctx context.Context, gridPoint nws.GridPoint, hours int) (GetHourlyForecastResponse, error) {
+	periods, err := g.NWSClient.GetSimplifiedHourlyForecastNPeriods(ctx, gridPoint.String(), hours)
+	if err != nil {
+		return GetHourlyForecastResponse{}, fmt.Errorf("failed to get simplified hourly forecast periods: %w", err)
+	}
+
+	periodsJSON, err := json.Marshal(periods)
+	if err != nil {
+		return GetHourlyForecastResponse{}, fmt.Errorf("failed to marshal simplified hourly forecast periods: %w", err)
+	}
+
+	prompt, err := g.Prompts.Render(prompts.HourlyForecastInformation, prompts.Data{
+		Input: string(periodsJSON),
+		Icons: Icons,
+	})
+	if err != nil {
+		return GetHourlyForecastResponse{}, err
+	}
+
+	var hfi HourlyForecastInformation
+	response, err := g.completeValidated(ctx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    4096,
+		Schema:       hourlyForecastInformationSchema,
+	}, func(content string) []string {
+		hfi = HourlyForecastInformation{}
+		err := json.Unmarshal([]byte(content), &hfi)
+		if err != nil {
+			return []string{fmt.Sprintf("output is not valid JSON: %s", err.Error())}
+		}
+
+		var problems []string
+		hfi.Periods, problems = validateHourlyForecastInformation(periods, hfi)
+		return problems
+	})
+
+	// the NWS forecast can stand in for any part of the output, so the hourly forecast never fails on the LLM
+	provider, promptVersion := RulesProvider, ""
+	if err != nil && !errors.Is(err, ErrInvalidOutput) {
+		slog.Warn("failed to get hourly forecast information, falling back to rule-based icons",
+			slog.String("error", err.Error()),
+			slog.String("grid_point", gridPoint.String()),
+		)
+		hfi = HourlyForecastInformation{}
+	} else {
+		if err != nil {
+			slog.Warn("hourly forecast information is incomplete, filling in from the nws forecast",
+				slog.String("error", err.Error()),
+				slog.String("grid_point", gridPoint.String()),
+			)
+		}
+		provider, promptVersion = response.Provider, prompt.Version
+	}
+
+	if hfi.Nowcast == "" && len(periods) > 0 {
+		hfi.Nowcast = periods[0].ShortForecast
+	}
+
+	joinedPeriods := make([]JoinedHourlyForecastPeriod, 0, len(periods))
+	for i, period := range periods {
+		hfpi := HourlyForecastPeriodInformation{Number: period.Number}
+		if i < len(hfi.Periods) {
+			hfpi = hfi.Periods[i]
+		}
+		hfpi.Icon = g.validIconOr(ctx, "hourly", hfpi.Icon, ruleBasedHourlyIcon(period))
+
+		joinedPeriods = append(joinedPeriods, JoinHourlyForecastPeriod(hfpi, period))
+	}
+
+	return GetHourlyForecastResponse{
+		Nowcast:       hfi.Nowcast,
+		Periods:       joinedPeriods,
+		LastUpdated:   time.Now(),
+		Provider:      provider,
+		PromptVersion: promptVersion,
+	}, nil
+}
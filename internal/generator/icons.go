@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RulesProvider is reported as the provider of forecast products whose icons were chosen by RuleBasedIcon
+// because the LLM failed
+const RulesProvider = "rules"
+
+// fallbackIcon is used when neither the NWS icon nor the short forecast matches a rule
+const fallbackIcon = "cloud"
+
+// dayNightIcon is an icon for day and for night
+type dayNightIcon struct {
+	day   string
+	night string
+}
+
+func (i dayNightIcon) icon(isDaytime bool) string {
+	if isDaytime {
+		return i.day
+	}
+	return i.night
+}
+
+// nwsIconCodes maps the NWS forecast icon condition codes to icons, see https://api.weather.gov/icons
+var nwsIconCodes = map[string]dayNightIcon{
+	"skc":             {"sun", "cloud-moon"},
+	"few":             {"sun", "cloud-moon"},
+	"sct":             {"cloud-sun", "cloud-moon"},
+	"bkn":             {"cloud-sun", "cloud-moon"},
+	"ovc":             {"cloudy", "cloudy"},
+	"wind_skc":        {"wind", "wind"},
+	"wind_few":        {"wind", "wind"},
+	"wind_sct":        {"wind", "wind"},
+	"wind_bkn":        {"wind", "wind"},
+	"wind_ovc":        {"wind", "wind"},
+	"snow":            {"cloud-snow", "cloud-snow"},
+	"rain_snow":       {"cloud-snow", "cloud-snow"},
+	"rain_sleet":      {"cloud-hail", "cloud-hail"},
+	"snow_sleet":      {"cloud-hail", "cloud-hail"},
+	"fzra":            {"cloud-hail", "cloud-hail"},
+	"rain_fzra":       {"cloud-hail", "cloud-hail"},
+	"snow_fzra":       {"cloud-hail", "cloud-hail"},
+	"sleet":           {"cloud-hail", "cloud-hail"},
+	"rain":            {"cloud-rain", "cloud-rain"},
+	"rain_showers":    {"cloud-sun-rain", "cloud-moon-rain"},
+	"rain_showers_hi": {"cloud-sun-rain", "cloud-moon-rain"},
+	"tsra":            {"cloud-lightning", "cloud-lightning"},
+	"tsra_sct":        {"cloud-lightning", "cloud-lightning"},
+	"tsra_hi":         {"cloud-lightning", "cloud-lightning"},
+	"tornado":         {"wind", "wind"},
+	"hurricane":       {"wind", "wind"},
+	"tropical_storm":  {"wind", "wind"},
+	"dust":            {"cloud-fog", "cloud-fog"},
+	"smoke":           {"cloud-fog", "cloud-fog"},
+	"haze":            {"cloud-fog", "cloud-fog"},
+	"fog":             {"cloud-fog", "cloud-fog"},
+	"hot":             {"thermometer-sun", "thermometer-sun"},
+	"cold":            {"thermometer-snowflake", "thermometer-snowflake"},
+	"blizzard":        {"snowflake", "snowflake"},
+}
+
+// shortForecastRule maps short forecasts containing any of keywords to an icon. Rules are checked in
+// order, so more specific weather comes first
+type shortForecastRule struct {
+	keywords []string
+	icon     dayNightIcon
+}
+
+var shortForecastRules = []shortForecastRule{
+	{[]string{"thunderstorm", "t-storm"}, dayNightIcon{"cloud-lightning", "cloud-lightning"}},
+	{[]string{"blizzard"}, dayNightIcon{"snowflake", "snowflake"}},
+	{[]string{"freezing", "sleet", "hail"}, dayNightIcon{"cloud-hail", "cloud-hail"}},
+	{[]string{"snow", "flurries"}, dayNightIcon{"cloud-snow", "cloud-snow"}},
+	{[]string{"drizzle"}, dayNightIcon{"cloud-drizzle", "cloud-drizzle"}},
+	{[]string{"showers"}, dayNightIcon{"cloud-sun-rain", "cloud-moon-rain"}},
+	{[]string{"rain"}, dayNightIcon{"cloud-rain", "cloud-rain"}},
+	{[]string{"fog", "haze", "smoke", "dust"}, dayNightIcon{"cloud-fog", "cloud-fog"}},
+	{[]string{"windy", "breezy", "blustery"}, dayNightIcon{"wind", "wind"}},
+	{[]string{"partly", "mostly sunny", "mostly clear", "mostly cloudy"}, dayNightIcon{"cloud-sun", "cloud-moon"}},
+	{[]string{"cloudy", "overcast"}, dayNightIcon{"cloudy", "cloudy"}},
+	{[]string{"sunny", "clear", "fair"}, dayNightIcon{"sun", "cloud-moon"}},
+	{[]string{"hot"}, dayNightIcon{"thermometer-sun", "thermometer-sun"}},
+	{[]string{"cold"}, dayNightIcon{"thermometer-snowflake", "thermometer-snowflake"}},
+}
+
+// RuleBasedIcon chooses an icon for a forecast period from the NWS icon URL, or failing that the short
+// forecast. Rain becomes cloud-rain-wind when the short forecast also mentions wind. ok is false when
+// no rule matched
+func RuleBasedIcon(nwsIcon string, shortForecast string, isDaytime bool) (string, bool) {
+	shortForecast = strings.ToLower(shortForecast)
+	windy := containsAny(shortForecast, "windy", "breezy", "blustery")
+
+	icon, ok := iconFromNWSIcon(nwsIcon)
+	if !ok {
+		icon, ok = iconFromShortForecast(shortForecast)
+	}
+	if !ok {
+		return "", false
+	}
+
+	if windy && icon.day == "cloud-rain" {
+		return "cloud-rain-wind", true
+	}
+
+	return icon.icon(isDaytime), true
+}
+
+// iconFromNWSIcon maps the first condition of an NWS icon URL
+func iconFromNWSIcon(nwsIcon string) (dayNightIcon, bool) {
+	forecastIcon, ok := nws.ParseIconURL(nwsIcon)
+	if !ok || len(forecastIcon.Conditions) == 0 {
+		return dayNightIcon{}, false
+	}
+
+	icon, ok := nwsIconCodes[forecastIcon.Conditions[0].Code]
+	return icon, ok
+}
+
+func iconFromShortForecast(shortForecast string) (dayNightIcon, bool) {
+	for _, rule := range shortForecastRules {
+		if containsAny(shortForecast, rule.keywords...) {
+			return rule.icon, true
+		}
+	}
+	return dayNightIcon{}, false
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleBasedIcon is RuleBasedIcon for a forecast period, using fallbackIcon when no rule matched
+func ruleBasedIcon(period nws.SimplifiedForecastPeriods) string {
+	icon, ok := RuleBasedIcon(period.Icon, period.ShortForecast, period.IsDaytime)
+	if !ok {
+		return fallbackIcon
+	}
+	return icon
+}
+
+// ruleBasedHourlyIcon is RuleBasedIcon for an hour, using fallbackIcon when no rule matched
+func ruleBasedHourlyIcon(period nws.SimplifiedHourlyForecastPeriod) string {
+	icon, ok := RuleBasedIcon(period.Icon, period.ShortForecast, period.IsDaytime)
+	if !ok {
+		return fallbackIcon
+	}
+	return icon
+}
+
+// validIconOr returns icon when it is one of Icons, or else the rule-based fallback, counting the fallback
+func (g *Generator) validIconOr(ctx context.Context, product string, icon string, fallback string) string {
+	if ValidIcon(icon) {
+		return icon
+	}
+
+	g.iconFallbacksCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("product", product)))
+	return fallback
+}
+
+// recordIconComparison counts whether the LLM chose the same icon for a period as the rules would have,
+// as a measure of how often the LLM disagrees with the NWS icon and short forecast
+func (g *Generator) recordIconComparison(ctx context.Context, product string, llmIcon string, period nws.SimplifiedForecastPeriods) {
+	result := "no_rule"
+	if icon, ok := RuleBasedIcon(period.Icon, period.ShortForecast, period.IsDaytime); ok {
+		result = "disagree"
+		if icon == llmIcon {
+			result = "agree"
+		}
+	}
+
+	g.iconComparisonsCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("product", product),
+		attribute.String("result", result),
+	))
+}
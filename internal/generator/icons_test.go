@@ -0,0 +1,70 @@
+package generator
+
+import "testing"
+
+func TestRuleBasedIcon(t *testing.T) {
+	tests := []struct {
+		name          string
+		nwsIcon       string
+		shortForecast string
+		isDaytime     bool
+		want          string
+		wantOK        bool
+	}{
+		{name: "clear day", nwsIcon: "https://api.weather.gov/icons/land/day/skc?size=medium", isDaytime: true, want: "sun", wantOK: true},
+		{name: "clear night", nwsIcon: "https://api.weather.gov/icons/land/night/skc?size=medium", isDaytime: false, want: "cloud-moon", wantOK: true},
+		{name: "first condition wins", nwsIcon: "https://api.weather.gov/icons/land/night/rain,40/tsra,60", want: "cloud-rain", wantOK: true},
+		{name: "showers at night", nwsIcon: "https://api.weather.gov/icons/land/night/rain_showers,30", want: "cloud-moon-rain", wantOK: true},
+		{name: "windy rain", nwsIcon: "https://api.weather.gov/icons/land/day/rain,80", shortForecast: "Rain And Breezy", isDaytime: true, want: "cloud-rain-wind", wantOK: true},
+		{name: "nws icon beats short forecast", nwsIcon: "https://api.weather.gov/icons/land/day/snow", shortForecast: "Sunny", isDaytime: true, want: "cloud-snow", wantOK: true},
+		{name: "unknown code falls back to short forecast", nwsIcon: "https://api.weather.gov/icons/land/day/unknown", shortForecast: "Patchy Fog", isDaytime: true, want: "cloud-fog", wantOK: true},
+		{name: "short forecast only", shortForecast: "Chance Showers And Thunderstorms", isDaytime: true, want: "cloud-lightning", wantOK: true},
+		{name: "short forecast ignores case", shortForecast: "MOSTLY CLOUDY", isDaytime: false, want: "cloud-moon", wantOK: true},
+		{name: "short forecast windy rain", shortForecast: "Rain And Windy", isDaytime: false, want: "cloud-rain-wind", wantOK: true},
+		{name: "not an nws icon", nwsIcon: "https://example.com/sunny.png", shortForecast: "Sunny", isDaytime: true, want: "sun", wantOK: true},
+		{name: "no rule", nwsIcon: "", shortForecast: "Unremarkable", isDaytime: true, want: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RuleBasedIcon(tt.nwsIcon, tt.shortForecast, tt.isDaytime)
+			if ok != tt.wantOK {
+				t.Errorf("RuleBasedIcon(%q, %q, %v) ok = %v, want %v", tt.nwsIcon, tt.shortForecast, tt.isDaytime, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("RuleBasedIcon(%q, %q, %v) = %q, want %q", tt.nwsIcon, tt.shortForecast, tt.isDaytime, got, tt.want)
+			}
+			if ok && !ValidIcon(got) {
+				t.Errorf("RuleBasedIcon(%q, %q, %v) = %q, which is not in Icons", tt.nwsIcon, tt.shortForecast, tt.isDaytime, got)
+			}
+		})
+	}
+}
+
+func TestRuleBasedIconsAreValid(t *testing.T) {
+	for code, icon := range nwsIconCodes {
+		for _, i := range []string{icon.day, icon.night} {
+			if !ValidIcon(i) {
+				t.Errorf("nws icon code %q maps to %q, which is not in Icons", code, i)
+			}
+		}
+	}
+
+	for _, rule := range shortForecastRules {
+		for _, i := range []string{rule.icon.day, rule.icon.night} {
+			if !ValidIcon(i) {
+				t.Errorf("short forecast rule %v maps to %q, which is not in Icons", rule.keywords, i)
+			}
+		}
+	}
+
+	if !ValidIcon(fallbackIcon) {
+		t.Errorf("fallback icon %q is not in Icons", fallbackIcon)
+	}
+}
+
+func TestWarningIconIsValid(t *testing.T) {
+	if !ValidIcon(WarningIcon) {
+		t.Errorf("warning icon %q is not in Icons", WarningIcon)
+	}
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+// GetForecastPeriodsInformation is the LLM's choice for a forecast period. Only the icon is subjective,
+// the time of day and Beaufort scale are computed from the NWS period when joining
+type GetForecastPeriodsInformation struct {
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+}
+
+// forecastPeriodsInformationOutput is the LLM output for GenerateForecastPeriodsInformation. Structured
+// output must be an object, so the periods are wrapped
+type forecastPeriodsInformationOutput struct {
+	Periods []GetForecastPeriodsInformation `json:"periods"`
+}
+
+type JoinedForecastPeriodsInformation struct {
+	Name                       string    `json:"name"`
+	TimeOfDay                  string    `json:"time_of_day"`
+	Icon                       string    `json:"icon"`
+	Beaufort                   string    `json:"beaufort"`
+	BeaufortForce              *int      `json:"beaufort_force"`
+	DetailedForecast           string    `json:"detailed_forecast"`
+	ShortForecast              string    `json:"short_forecast"`
+	StartTime                  time.Time `json:"start_time"`
+	EndTime                    time.Time `json:"end_time"`
+	Temperature                int       `json:"temperature"`
+	ProbabilityOfPrecipitation int       `json:"probability_of_precipitation"`
+	PrecipitationAmount        *float64  `json:"precipitation_amount"`
+	SnowfallAmount             *float64  `json:"snowfall_amount"`
+	WindSpeed                  string    `json:"wind_speed"`
+	WindGust                   *float64  `json:"wind_gust"`
+	WindDirection              string    `json:"wind_direction"`
+}
+
+func JoinForecastPeriodsInformation(fpi GetForecastPeriodsInformation, period nws.SimplifiedForecastPeriods) JoinedForecastPeriodsInformation {
+	var beaufortForce *int
+	force, beaufort, ok := nws.Beaufort(period.WindSpeed)
+	if ok {
+		beaufortForce = &force
+	}
+
+	return JoinedForecastPeriodsInformation{
+		Name:                       period.Name,
+		TimeOfDay:                  TimeOfDay(period.IsDaytime),
+		Icon:                       fpi.Icon,
+		Beaufort:                   beaufort,
+		BeaufortForce:              beaufortForce,
+		DetailedForecast:           period.DetailedForecast,
+		ShortForecast:              period.ShortForecast,
+		StartTime:                  period.StartTime,
+		EndTime:                    period.EndTime,
+		Temperature:                period.Temperature,
+		ProbabilityOfPrecipitation: period.ProbabilityOfPrecipitation,
+		PrecipitationAmount:        period.PrecipitationAmount,
+		SnowfallAmount:             period.SnowfallAmount,
+		WindSpeed:                  period.WindSpeed,
+		WindGust:                   period.WindGust,
+		WindDirection:              period.WindDirection,
+	}
+}
+
+// TimeOfDay returns "day" or "night" for an NWS period
+func TimeOfDay(isDaytime bool) string {
+	if isDaytime {
+		return "day"
+	}
+	return "night"
+}
+
+type GetForecastPeriodsInformationResponse struct {
+	Periods     []JoinedForecastPeriodsInformation `json:"periods"`
+	LastUpdated time.Time                          `json:"last_updated"`
+	Stale       bool                               `json:"stale"`
+	Degraded    bool                               `json:"degraded"`
+
+	// ForecastUpdateTime is when the NWS forecast the periods were generated from was last updated
+	ForecastUpdateTime time.Time `json:"forecast_update_time"`
+
+	// Provider is the name of the LLM provider that generated the periods information
+	Provider string `json:"provider"`
+
+	// PromptVersion is the version of the prompt template the periods information was generated with,
+	// empty when the icons were chosen by rules
+	PromptVersion string `json:"prompt_version"`
+}
+
+// GenerateForecastPeriodsInformation enriches every forecast period for a grid point with an LLM-chosen
+// icon, and the time of day and Beaufort wind scale classification computed from the NWS period
+func (g *Generator) GenerateForecastPeriodsInformation(ctx context.Context, gridPoint nws.GridPoint) (GetForecastPeriodsInformationResponse, error) {
+	forecast, err := g.NWSClient.GetForecast(ctx, gridPoint.String())
+	if err != nil {
+		return GetForecastPeriodsInformationResponse{}, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	periods := nws.SimplifyForecastNPeriods(forecast, -1)
+	g.addQuantitativeForecast(ctx, gridPoint, periods)
+
+	periodsJSON, err := json.Marshal(periods)
+	if err != nil {
+		return GetForecastPeriodsInformationResponse{}, fmt.Errorf("failed to marshal simplified forecast periods: %w", err)
+	}
+
+	prompt, err := g.Prompts.Render(prompts.ForecastPeriodsInformation, prompts.Data{
+		Input: string(periodsJSON),
+		Icons: Icons,
+	})
+	if err != nil {
+		return GetForecastPeriodsInformationResponse{}, err
+	}
+
+	var fpi []GetForecastPeriodsInformation
+	response, err := g.completeValidated(ctx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    4096,
+		Schema:       forecastPeriodsInformationSchema,
+	}, func(content string) []string {
+		var fpio forecastPeriodsInformationOutput
+		err := json.Unmarshal([]byte(content), &fpio)
+		if err != nil {
+			return []string{fmt.Sprintf("output is not valid JSON: %s", err.Error())}
+		}
+
+		var problems []string
+		fpi, problems = validateForecastPeriodsInformation(periods, fpio.Periods)
+		return problems
+	})
+
+	// the icons are the only LLM output, so rules can stand in for any of them rather than failing the forecast
+	if err != nil && !errors.Is(err, ErrInvalidOutput) {
+		slog.Warn("failed to get forecast periods information, falling back to rule-based icons",
+			slog.String("error", err.Error()),
+			slog.String("grid_point", gridPoint.String()),
+		)
+		fpi = nil
+	} else if err != nil {
+		slog.Warn("forecast periods information is incomplete, falling back to rule-based icons for invalid periods",
+			slog.String("error", err.Error()),
+			slog.String("grid_point", gridPoint.String()),
+		)
+	}
+
+	var llmIcons int
+	joinedPeriods := make([]JoinedForecastPeriodsInformation, 0, len(periods))
+	for i, period := range periods {
+		info := GetForecastPeriodsInformation{Name: period.Name}
+		if i < len(fpi) {
+			info = fpi[i]
+		}
+
+		if ValidIcon(info.Icon) {
+			llmIcons++
+			g.recordIconComparison(ctx, "detailed", info.Icon, period)
+		}
+		info.Icon = g.validIconOr(ctx, "detailed", info.Icon, ruleBasedIcon(period))
+
+		joinedPeriods = append(joinedPeriods, JoinForecastPeriodsInformation(info, period))
+	}
+
+	provider, promptVersion := RulesProvider, ""
+	if llmIcons > 0 {
+		provider, promptVersion = response.Provider, prompt.Version
+	}
+
+	return GetForecastPeriodsInformationResponse{
+		Periods:            joinedPeriods,
+		LastUpdated:        time.Now(),
+		ForecastUpdateTime: forecast.Properties.UpdateTime,
+		Provider:           provider,
+		PromptVersion:      promptVersion,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"slices"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+)
+
+// Icons is the set of icons the LLM may choose from
+var Icons = []string{
+	"cloud",
+	"cloud-drizzle",
+	"cloud-fog",
+	"cloud-hail",
+	"cloud-lightning",
+	"cloud-moon",
+	"cloud-moon-rain",
+	"cloud-rain",
+	"cloud-rain-wind",
+	"cloud-snow",
+	"cloud-sun",
+	"cloud-sun-rain",
+	"cloudy",
+	"snowflake",
+	"sun",
+	"sun-snow",
+	"thermometer-snowflake",
+	"thermometer-sun",
+	"triangle-alert",
+	"wind",
+}
+
+// IconSchema is the JSON schema of an icon chosen by the LLM
+func IconSchema() map[string]any {
+	return map[string]any{
+		"type": "string",
+		"enum": Icons,
+	}
+}
+
+// ObjectSchema is the JSON schema of an object in which every property is required, as strict structured
+// output requires
+func ObjectSchema(properties map[string]any) map[string]any {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	slices.Sort(required)
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+var forecastSummarySchema = &llm.JSONSchema{
+	Name:        "forecast_summary",
+	Description: "A summary of the forecast and the icon that best fits it",
+	Schema: ObjectSchema(map[string]any{
+		"summary": map[string]any{"type": "string"},
+		"icon":    IconSchema(),
+	}),
+}
+
+var forecastPeriodsInformationSchema = &llm.JSONSchema{
+	Name:        "forecast_periods_information",
+	Description: "The icon of every forecast period",
+	Schema: ObjectSchema(map[string]any{
+		"periods": map[string]any{
+			"type": "array",
+			"items": ObjectSchema(map[string]any{
+				"name": map[string]any{"type": "string"},
+				"icon": IconSchema(),
+			}),
+		},
+	}),
+}
+
+var hourlyForecastInformationSchema = &llm.JSONSchema{
+	Name:        "hourly_forecast_information",
+	Description: "A nowcast and the icon of every hour",
+	Schema: ObjectSchema(map[string]any{
+		"nowcast": map[string]any{"type": "string"},
+		"periods": map[string]any{
+			"type": "array",
+			"items": ObjectSchema(map[string]any{
+				"number": map[string]any{"type": "integer"},
+				"icon":   IconSchema(),
+			}),
+		},
+	}),
+}
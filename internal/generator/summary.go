@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+// WarningIcon replaces the LLM's icon choice whenever a warning-level alert is in effect
+const WarningIcon = "triangle-alert"
+
+type ForecastSummaryResponse struct {
+	Summary     string         `json:"summary"`
+	Icon        string         `json:"icon"`
+	Severity    string         `json:"severity"`
+	Alerts      []SummaryAlert `json:"alerts"`
+	LastUpdated time.Time      `json:"last_updated"`
+	Stale       bool           `json:"stale"`
+	Degraded    bool           `json:"degraded"`
+
+	// ForecastUpdateTime is when the NWS forecast the summary was generated from was last updated
+	ForecastUpdateTime time.Time `json:"forecast_update_time"`
+
+	// Provider is the name of the LLM provider that generated the summary
+	Provider string `json:"provider"`
+
+	// PromptVersion is the version of the prompt template the summary was generated with
+	PromptVersion string `json:"prompt_version"`
+}
+
+// SummaryAlert is the subset of an active alert that is given to the LLM and returned with a summary
+type SummaryAlert struct {
+	Event    string    `json:"event"`
+	Headline string    `json:"headline"`
+	Severity string    `json:"severity"`
+	Urgency  string    `json:"urgency"`
+	Expires  time.Time `json:"expires"`
+}
+
+type forecastSummaryInput struct {
+	Alerts  []SummaryAlert                  `json:"alerts"`
+	Periods []nws.SimplifiedForecastPeriods `json:"periods"`
+}
+
+// GenerateForecastSummary generates a short summary of the next three forecast periods for a grid point,
+// taking any active alerts for the grid point into account
+func (g *Generator) GenerateForecastSummary(ctx context.Context, gridPoint nws.GridPoint) (ForecastSummaryResponse, error) {
+	forecast, err := g.NWSClient.GetForecast(ctx, gridPoint.String())
+	if err != nil {
+		return ForecastSummaryResponse{}, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	periods := nws.SimplifyForecastNPeriods(forecast, 3)
+	g.addQuantitativeForecast(ctx, gridPoint, periods)
+
+	// a summary without alerts is still useful, so alert failures are not fatal
+	alerts, err := g.getActiveAlerts(ctx, forecast)
+	if err != nil {
+		slog.Error("failed to get active alerts for forecast summary", slog.String("error", err.Error()), slog.String("grid_point", gridPoint.String()))
+	}
+
+	summaryAlerts := make([]SummaryAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		summaryAlerts = append(summaryAlerts, SummaryAlert{
+			Event:    alert.Event,
+			Headline: alert.Headline,
+			Severity: alert.Severity,
+			Urgency:  alert.Urgency,
+			Expires:  alert.Expires,
+		})
+	}
+
+	inputJSON, err := json.Marshal(forecastSummaryInput{
+		Alerts:  summaryAlerts,
+		Periods: periods,
+	})
+	if err != nil {
+		return ForecastSummaryResponse{}, fmt.Errorf("failed to marshal forecast summary input: %w", err)
+	}
+
+	prompt, err := g.Prompts.Render(prompts.ForecastSummary, prompts.Data{
+		Input: string(inputJSON),
+		Icons: Icons,
+	})
+	if err != nil {
+		return ForecastSummaryResponse{}, err
+	}
+
+	var fsr ForecastSummaryResponse
+	response, err := g.completeValidated(ctx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    4096,
+		Schema:       forecastSummarySchema,
+	}, func(content string) []string {
+		fsr = ForecastSummaryResponse{}
+		err := json.Unmarshal([]byte(content), &fsr)
+		if err != nil {
+			return []string{fmt.Sprintf("output is not valid JSON: %s", err.Error())}
+		}
+
+		return validateForecastSummary(fsr)
+	})
+
+	// only the summary itself needs the LLM, so an invalid icon is replaced by the rule-based one
+	if err != nil && (!errors.Is(err, ErrInvalidOutput) || strings.TrimSpace(fsr.Summary) == "") {
+		return ForecastSummaryResponse{}, fmt.Errorf("failed to get forecast summary: %w", err)
+	} else if err != nil {
+		slog.Warn("forecast summary icon is invalid, falling back to a rule-based icon",
+			slog.String("error", err.Error()),
+			slog.String("grid_point", gridPoint.String()),
+		)
+	}
+
+	fallback := fallbackIcon
+	if len(periods) > 0 {
+		fallback = ruleBasedIcon(periods[0])
+		if ValidIcon(fsr.Icon) {
+			g.recordIconComparison(ctx, "summary", fsr.Icon, periods[0])
+		}
+	}
+	fsr.Icon = g.validIconOr(ctx, "summary", fsr.Icon, fallback)
+
+	fsr.Alerts = summaryAlerts
+	fsr.Severity = nws.MaxSeverity(alerts)
+	for _, alert := range alerts {
+		if alert.IsWarning() {
+			fsr.Icon = WarningIcon
+			break
+		}
+	}
+
+	fsr.LastUpdated = time.Now()
+	fsr.ForecastUpdateTime = forecast.Properties.UpdateTime
+	fsr.Provider = response.Provider
+	fsr.PromptVersion = prompt.Version
+
+	return fsr, nil
+}
+
+// getActiveAlerts returns the alerts in effect at the center of the forecast's grid cell
+func (g *Generator) getActiveAlerts(ctx context.Context, forecast nws.ForecastResponse) ([]nws.SimplifiedAlert, error) {
+	lat, lon, err := forecast.Centroid()
+	if err != nil {
+		return nil, err
+	}
+
+	return g.NWSClient.GetSimplifiedActiveAlerts(ctx, lat, lon)
+}
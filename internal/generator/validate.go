@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+var (
+	ErrInvalidOutput = errors.New("invalid llm output")
+)
+
+// ValidIcon reports whether icon is one of Icons
+func ValidIcon(icon string) bool {
+	return slices.Contains(Icons, icon)
+}
+
+// validator parses and checks LLM output, returning every problem found. It is called again on the
+// repaired output, so a result captured by the validator is always from the returned response
+type validator func(content string) []string
+
+// completeValidated sends a completion request and validates the response. When the response is invalid
+// the LLM is asked once to repair it, given its previous output and the specific problems, before giving up.
+// Unrepairable output, or the first output when the repair request itself fails, is returned along with
+// ErrInvalidOutput, so callers can still use its valid parts
+func (g *Generator) completeValidated(ctx context.Context, req llm.CompletionRequest, validate validator) (*llm.CompletionResponse, error) {
+	response, err := g.LLMProvider.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := validate(StripMarkdownCodeBlock(response.Content))
+	if len(problems) == 0 {
+		return response, nil
+	}
+
+	slog.Warn("llm output failed validation, requesting repair",
+		slog.String("provider", response.Provider),
+		slog.String("problems", strings.Join(problems, "; ")),
+	)
+
+	repairReq := req
+	repairReq.UserPrompt = buildRepairPrompt(req.UserPrompt, response.Content, problems)
+
+	repaired, err := g.LLMProvider.Complete(ctx, repairReq)
+	if err != nil {
+		return response, fmt.Errorf("%w: %s, and failed to repair llm output: %w", ErrInvalidOutput, strings.Join(problems, "; "), err)
+	}
+
+	problems = validate(StripMarkdownCodeBlock(repaired.Content))
+	if len(problems) > 0 {
+		slog.Debug("unrepairable llm output", slog.String("response", repaired.Content))
+		return repaired, fmt.Errorf("%w: %s", ErrInvalidOutput, strings.Join(problems, "; "))
+	}
+
+	return repaired, nil
+}
+
+func buildRepairPrompt(prompt string, output string, problems []string) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\n<previous_output>")
+	sb.WriteString(output)
+	sb.WriteString("</previous_output>\n\nYour previous output for this input had the following problems:\n")
+	for _, problem := range problems {
+		sb.WriteString("- ")
+		sb.WriteString(problem)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Return the complete corrected output, following the original instructions.")
+	return sb.String()
+}
+
+// validateForecastSummary checks the icon of a forecast summary
+func validateForecastSummary(fsr ForecastSummaryResponse) []string {
+	problems := make([]string, 0)
+	if strings.TrimSpace(fsr.Summary) == "" {
+		problems = append(problems, "summary is empty")
+	}
+	if !ValidIcon(fsr.Icon) {
+		problems = append(problems, fmt.Sprintf("icon %q is not in the list of icons", fsr.Icon))
+	}
+	return problems
+}
+
+// validateForecastPeriodsInformation checks the output for every forecast period against the NWS periods
+// it was generated from. Period names are matched ignoring case and surrounding whitespace, so the
+// returned output is in period order with the NWS names, with an empty icon for any period that is missing
+func validateForecastPeriodsInformation(periods []nws.SimplifiedForecastPeriods, output []GetForecastPeriodsInformation) ([]GetForecastPeriodsInformation, []string) {
+	problems := make([]string, 0)
+
+	byName := make(map[string]GetForecastPeriodsInformation, len(output))
+	for _, fpi := range output {
+		key := strings.ToLower(strings.TrimSpace(fpi.Name))
+		if _, ok := byName[key]; ok {
+			problems = append(problems, fmt.Sprintf("period %q appears more than once", fpi.Name))
+			continue
+		}
+		byName[key] = fpi
+	}
+
+	validated := make([]GetForecastPeriodsInformation, 0, len(periods))
+	for _, period := range periods {
+		key := strings.ToLower(strings.TrimSpace(period.Name))
+		fpi, ok := byName[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("period %q is missing", period.Name))
+			validated = append(validated, GetForecastPeriodsInformation{Name: period.Name})
+			continue
+		}
+		delete(byName, key)
+
+		fpi.Name = period.Name
+		if !ValidIcon(fpi.Icon) {
+			problems = append(problems, fmt.Sprintf("icon %q for period %q is not in the list of icons", fpi.Icon, period.Name))
+		}
+
+		validated = append(validated, fpi)
+	}
+
+	for _, fpi := range output {
+		key := strings.ToLower(strings.TrimSpace(fpi.Name))
+		if _, ok := byName[key]; ok {
+			problems = append(problems, fmt.Sprintf("period %q is not in the input", fpi.Name))
+			delete(byName, key)
+		}
+	}
+
+	return validated, problems
+}
+
+// validateHourlyForecastInformation checks the output for every hour against the NWS hours it was generated
+// from. The returned output is in hour order, with an empty icon for any hour that is missing
+func validateHourlyForecastInformation(periods []nws.SimplifiedHourlyForecastPeriod, output HourlyForecastInformation) ([]HourlyForecastPeriodInformation, []string) {
+	problems := make([]string, 0)
+	if strings.TrimSpace(output.Nowcast) == "" {
+		problems = append(problems, "nowcast is empty")
+	}
+
+	byNumber := make(map[int]HourlyForecastPeriodInformation, len(output.Periods))
+	for _, hfpi := range output.Periods {
+		if _, ok := byNumber[hfpi.Number]; ok {
+			problems = append(problems, fmt.Sprintf("hour %d appears more than once", hfpi.Number))
+			continue
+		}
+		byNumber[hfpi.Number] = hfpi
+	}
+
+	validated := make([]HourlyForecastPeriodInformation, 0, len(periods))
+	for _, period := range periods {
+		hfpi, ok := byNumber[period.Number]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("hour %d is missing", period.Number))
+			validated = append(validated, HourlyForecastPeriodInformation{Number: period.Number})
+			continue
+		}
+		delete(byNumber, period.Number)
+
+		if !ValidIcon(hfpi.Icon) {
+			problems = append(problems, fmt.Sprintf("icon %q for hour %d is not in the list of icons", hfpi.Icon, period.Number))
+		}
+
+		validated = append(validated, hfpi)
+	}
+
+	for _, hfpi := range output.Periods {
+		if _, ok := byNumber[hfpi.Number]; ok {
+			problems = append(problems, fmt.Sprintf("hour %d is not in the input", hfpi.Number))
+			delete(byNumber, hfpi.Number)
+		}
+	}
+
+	return validated, problems
+}
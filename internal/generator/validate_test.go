@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+func TestValidateForecastSummary(t *testing.T) {
+	tests := []struct {
+		name         string
+		fsr          ForecastSummaryResponse
+		wantProblems int
+	}{
+		{name: "valid", fsr: ForecastSummaryResponse{Summary: "Sunny and warm.", Icon: "sun"}, wantProblems: 0},
+		{name: "empty summary", fsr: ForecastSummaryResponse{Summary: "  ", Icon: "sun"}, wantProblems: 1},
+		{name: "unknown icon", fsr: ForecastSummaryResponse{Summary: "Sunny and warm.", Icon: "sunny"}, wantProblems: 1},
+		{name: "empty", fsr: ForecastSummaryResponse{}, wantProblems: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateForecastSummary(tt.fsr)
+			if len(problems) != tt.wantProblems {
+				t.Errorf("validateForecastSummary() problems = %q, want %d problems", problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestValidateForecastPeriodsInformation(t *testing.T) {
+	periods := []nws.SimplifiedForecastPeriods{
+		{Name: "Tonight"},
+		{Name: "Wednesday"},
+	}
+
+	tests := []struct {
+		name         string
+		output       []GetForecastPeriodsInformation
+		want         []GetForecastPeriodsInformation
+		wantProblems int
+	}{
+		{
+			name:   "valid",
+			output: []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}},
+			want:   []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}},
+		},
+		{
+			name:   "names corrected and reordered",
+			output: []GetForecastPeriodsInformation{{Name: " wednesday", Icon: "sun"}, {Name: "TONIGHT ", Icon: "cloud-moon"}},
+			want:   []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}},
+		},
+		{
+			name:         "missing period",
+			output:       []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}},
+			want:         []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "unknown icon",
+			output:       []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "moon"}, {Name: "Wednesday", Icon: "sun"}},
+			want:         []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "moon"}, {Name: "Wednesday", Icon: "sun"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "duplicate period",
+			output:       []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Tonight", Icon: "cloudy"}, {Name: "Wednesday", Icon: "sun"}},
+			want:         []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "extra period",
+			output:       []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}, {Name: "Thursday", Icon: "sun"}},
+			want:         []GetForecastPeriodsInformation{{Name: "Tonight", Icon: "cloud-moon"}, {Name: "Wednesday", Icon: "sun"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "empty",
+			output:       nil,
+			want:         []GetForecastPeriodsInformation{{Name: "Tonight"}, {Name: "Wednesday"}},
+			wantProblems: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := validateForecastPeriodsInformation(periods, tt.output)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("validateForecastPeriodsInformation() = %+v, want %+v", got, tt.want)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("validateForecastPeriodsInformation() problems = %q, want %d problems", problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestValidateHourlyForecastInformation(t *testing.T) {
+	periods := []nws.SimplifiedHourlyForecastPeriod{
+		{Number: 1},
+		{Number: 2},
+	}
+
+	tests := []struct {
+		name         string
+		output       HourlyForecastInformation
+		want         []HourlyForecastPeriodInformation
+		wantProblems int
+	}{
+		{
+			name:   "valid",
+			output: HourlyForecastInformation{Nowcast: "Clear.", Periods: []HourlyForecastPeriodInformation{{Number: 2, Icon: "cloud-moon"}, {Number: 1, Icon: "sun"}}},
+			want:   []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 2, Icon: "cloud-moon"}},
+		},
+		{
+			name:         "empty nowcast",
+			output:       HourlyForecastInformation{Periods: []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 2, Icon: "cloud-moon"}}},
+			want:         []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 2, Icon: "cloud-moon"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "missing hour",
+			output:       HourlyForecastInformation{Nowcast: "Clear.", Periods: []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}}},
+			want:         []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 2}},
+			wantProblems: 1,
+		},
+		{
+			name:         "unknown icon",
+			output:       HourlyForecastInformation{Nowcast: "Clear.", Periods: []HourlyForecastPeriodInformation{{Number: 1, Icon: "sunny"}, {Number: 2, Icon: "cloud-moon"}}},
+			want:         []HourlyForecastPeriodInformation{{Number: 1, Icon: "sunny"}, {Number: 2, Icon: "cloud-moon"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "duplicate and extra hours",
+			output:       HourlyForecastInformation{Nowcast: "Clear.", Periods: []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 1, Icon: "cloud"}, {Number: 2, Icon: "cloud-moon"}, {Number: 3, Icon: "cloud-moon"}}},
+			want:         []HourlyForecastPeriodInformation{{Number: 1, Icon: "sun"}, {Number: 2, Icon: "cloud-moon"}},
+			wantProblems: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := validateHourlyForecastInformation(periods, tt.output)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("validateHourlyForecastInformation() = %+v, want %+v", got, tt.want)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("validateHourlyForecastInformation() problems = %q, want %d problems", problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+// scriptedProvider returns its responses in order, recording every request
+type scriptedProvider struct {
+	responses []string
+	requests  []llm.CompletionRequest
+}
+
+func (p *scriptedProvider) Complete(_ context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.requests = append(p.requests, req)
+	if len(p.requests) > len(p.responses) {
+		return nil, errors.New("no more responses")
+	}
+	return &llm.CompletionResponse{Content: p.responses[len(p.requests)-1], Provider: p.Name()}, nil
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func TestCompleteValidated(t *testing.T) {
+	validate := func(content string) []string {
+		if content != "valid" {
+			return []string{"output is not valid"}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		responses    []string
+		want         string
+		wantRequests int
+		wantErr      error
+	}{
+		{name: "valid", responses: []string{"valid"}, want: "valid", wantRequests: 1},
+		{name: "repaired", responses: []string{"invalid", "valid"}, want: "valid", wantRequests: 2},
+		{name: "markdown stripped", responses: []string{"```json\nvalid\n```"}, want: "```json\nvalid\n```", wantRequests: 1},
+		{name: "unrepairable", responses: []string{"invalid", "still invalid"}, want: "still invalid", wantRequests: 2, wantErr: ErrInvalidOutput},
+		{name: "repair failed", responses: []string{"invalid"}, want: "invalid", wantRequests: 2, wantErr: ErrInvalidOutput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{responses: tt.responses}
+			g := &Generator{LLMProvider: provider}
+
+			response, err := g.completeValidated(context.Background(), llm.CompletionRequest{UserPrompt: "prompt"}, validate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("completeValidated() error = %v, want %v", err, tt.wantErr)
+			}
+			if response == nil || response.Content != tt.want {
+				t.Errorf("completeValidated() response = %+v, want content %q", response, tt.want)
+			}
+			if len(provider.requests) != tt.wantRequests {
+				t.Errorf("completeValidated() sent %d requests, want %d", len(provider.requests), tt.wantRequests)
+			}
+
+			if len(provider.requests) > 1 {
+				repair := provider.requests[1].UserPrompt
+				if !strings.HasPrefix(repair, "prompt") || !strings.Contains(repair, tt.responses[0]) || !strings.Contains(repair, "output is not valid") {
+					t.Errorf("repair prompt %q does not include the prompt, previous output and problems", repair)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+type AlertResponse struct {
+	nws.SimplifiedAlert
+	Explanation string `json:"explanation"`
+
+	// Provider is the name of the LLM provider that generated the explanation, empty when it failed
+	Provider string `json:"provider"`
+}
+
+// alertExplanation is what is cached for an alert revision
+type alertExplanation struct {
+	Explanation string `json:"explanation"`
+	Provider    string `json:"provider"`
+}
+
+type GetAlertsResponse struct {
+	Alerts      []AlertResponse `json:"alerts"`
+	LastUpdated time.Time       `json:"last_updated"`
+}
+
+func (lh *LLMHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := lh.coordinatesFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine coordinates", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine coordinates"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine coordinates: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	cacheKey := lh.Cache.Key("alerts", fmt.Sprintf("%.4f,%.4f", lat, lon))
+
+	res, err := lh.Cache.Get(timeoutCtx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get alerts from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		_, _ = w.Write(res)
+		return
+	}
+
+	alerts, err := lh.NWSClient.GetSimplifiedActiveAlerts(timeoutCtx, lat, lon)
+	if err != nil {
+		slog.Error("failed to get active alerts", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get active alerts"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get active alerts: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	alertResponses := make([]AlertResponse, len(alerts))
+	explanationErrs := make([]error, len(alerts))
+
+	var wg sync.WaitGroup
+	for i, alert := range alerts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ae, err := lh.getAlertExplanation(timeoutCtx, alert)
+			explanationErrs[i] = err
+			if err != nil {
+				slog.Error("failed to get alert explanation", slog.String("error", err.Error()), slog.String("alert_id", alert.ID))
+			}
+
+			alertResponses[i] = AlertResponse{
+				SimplifiedAlert: alert,
+				Explanation:     ae.Explanation,
+				Provider:        ae.Provider,
+			}
+		}()
+	}
+	wg.Wait()
+
+	gaResponse := GetAlertsResponse{
+		Alerts:      alertResponses,
+		LastUpdated: time.Now(),
+	}
+
+	gaJson, err := json.Marshal(gaResponse)
+	if err != nil {
+		slog.Error("failed to marshal alerts", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal alerts"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal alerts: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	// only cache complete responses so missing explanations are retried on the next request
+	if errors.Join(explanationErrs...) == nil {
+		err = lh.Cache.Set(timeoutCtx, cacheKey, gaJson, lh.AlertsCacheDuration)
+		if err != nil {
+			slog.Error("could not set alerts in cache", slog.String("error", err.Error()))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(gaJson)
+}
+
+// getAlertExplanation returns a plain-language explanation of an alert. Alert IDs change with every
+// revision, so explanations are cached by ID and prompt version and only generated once per revision
+func (lh *LLMHandler) getAlertExplanation(ctx context.Context, alert nws.SimplifiedAlert) (alertExplanation, error) {
+	cacheKey := lh.Cache.Key("alert-explanation", alert.ID, lh.Prompts.Version(prompts.AlertExplanation))
+
+	res, err := lh.Cache.Get(ctx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get alert explanation from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		var ae alertExplanation
+		if err := json.Unmarshal(res, &ae); err == nil {
+			return ae, nil
+		}
+		slog.Error("could not unmarshal alert explanation from cache", slog.String("key", cacheKey))
+	}
+
+	alertJSON, err := json.Marshal(alert)
+	if err != nil {
+		return alertExplanation{}, fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	prompt, err := lh.Prompts.Render(prompts.AlertExplanation, prompts.Data{
+		Input: string(alertJSON),
+	})
+	if err != nil {
+		return alertExplanation{}, err
+	}
+
+	response, err := lh.LLMProvider.Complete(ctx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    1024,
+	})
+	if err != nil {
+		return alertExplanation{}, fmt.Errorf("failed to get alert explanation: %w", err)
+	}
+
+	ae := alertExplanation{
+		Explanation: strings.TrimSpace(response.Content),
+		Provider:    response.Provider,
+	}
+
+	// keep the explanation around for as long as the alert can still be returned
+	expiration := lh.Cache.CacheResultsDuration
+	if alert.Ends != nil && time.Until(*alert.Ends) > expiration {
+		expiration = time.Until(*alert.Ends)
+	} else if time.Until(alert.Expires) > expiration {
+		expiration = time.Until(alert.Expires)
+	}
+
+	aeJSON, err := json.Marshal(ae)
+	if err != nil {
+		slog.Error("could not marshal alert explanation for cache", slog.String("error", err.Error()))
+		return ae, nil
+	}
+
+	err = lh.Cache.Set(ctx, cacheKey, aeJSON, expiration)
+	if err != nil {
+		slog.Error("could not set alert explanation in cache", slog.String("error", err.Error()))
+	}
+
+	return ae, nil
+}
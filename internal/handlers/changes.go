@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+const defaultChangesWindow = 12 * time.Hour
+
+var (
+	ErrNoPreviousForecast = errors.New("no previous forecast")
+)
+
+// sinceFromRequest parses the since query parameter as an RFC 3339 time, defaulting to defaultChangesWindow ago
+func sinceFromRequest(r *http.Request) (time.Time, error) {
+	if !r.URL.Query().Has("since") {
+		return time.Now().Add(-defaultChangesWindow), nil
+	}
+
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be an RFC 3339 time: %w", err)
+	}
+
+	return since, nil
+}
+
+// previousForecast returns the archived detailed forecast to compare against: the last one generated at
+// or before since, or failing that the first one generated after it
+func (lh *LLMHandler) previousForecast(ctx context.Context, gridPoint nws.GridPoint, since time.Time) (history.Record, error) {
+	records, err := lh.History.Range(ctx, gridPoint, history.ProductDetailed, since.Add(-lh.History.Retention), since)
+	if err != nil {
+		return history.Record{}, err
+	}
+	if len(records) > 0 {
+		return records[len(records)-1], nil
+	}
+
+	records, err = lh.History.Range(ctx, gridPoint, history.ProductDetailed, since, time.Now())
+	if err != nil {
+		return history.Record{}, err
+	}
+	if len(records) > 0 {
+		return records[0], nil
+	}
+
+	return history.Record{}, fmt.Errorf("%w: no detailed forecast has been archived for %s", ErrNoPreviousForecast, gridPoint.String())
+}
+
+func (lh *LLMHandler) GetForecastChanges(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	since, err := sinceFromRequest(r)
+	if err != nil {
+		slog.Error("invalid since", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("invalid since"),
+			rfc9457.WithDetail(err.Error()),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	previous, err := lh.previousForecast(timeoutCtx, gridPoint, since)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNoPreviousForecast) {
+			status = http.StatusNotFound
+		}
+
+		slog.Error("failed to get previous forecast", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get previous forecast"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get previous forecast: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(status),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	var previousForecast generator.GetForecastPeriodsInformationResponse
+	err = json.Unmarshal(previous.Forecast, &previousForecast)
+	if err != nil {
+		slog.Error("could not unmarshal previous forecast", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("could not unmarshal previous forecast"),
+			rfc9457.WithDetail(fmt.Sprintf("could not unmarshal previous forecast: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	previousPeriods := make([]nws.SimplifiedForecastPeriods, 0, len(previousForecast.Periods))
+	for _, period := range previousForecast.Periods {
+		previousPeriods = append(previousPeriods, period.Simplified())
+	}
+
+	fcr, err := lh.Generator.CompareForecast(timeoutCtx, gridPoint, previousPeriods)
+	if err != nil {
+		slog.Error("failed to compare forecast", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to compare forecast"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to compare forecast: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+	fcr.PreviousGeneratedAt = previous.GeneratedAt
+	fcr.PreviousForecastUpdateTime = previous.ForecastUpdateTime
+
+	// the LLM is only asked to explain changes that are worth telling users about
+	if fcr.Material {
+		fcr.PromptVersion = lh.Prompts.Version(prompts.ForecastChanges)
+		fce, err := lh.getForecastChangesExplanation(timeoutCtx, gridPoint, fcr)
+		if err != nil {
+			slog.Error("failed to explain forecast changes", slog.String("error", err.Error()))
+			rfc9457.NewRFC9457(
+				rfc9457.WithTitle("failed to explain forecast changes"),
+				rfc9457.WithDetail(fmt.Sprintf("failed to explain forecast changes: %s", err.Error())),
+				rfc9457.WithInstance(r.URL.Path),
+				rfc9457.WithStatus(http.StatusInternalServerError),
+			).ServeHTTP(w, r)
+			return
+		}
+		fcr.Explanation = fce.Explanation
+		fcr.Provider = fce.Provider
+	}
+
+	fcrJson, err := json.Marshal(fcr)
+	if err != nil {
+		slog.Error("failed to marshal forecast changes", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal forecast changes"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal forecast changes: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(fcrJson)
+}
+
+// getForecastChangesExplanation returns an explanation of the material changes between two forecasts. The
+// changes only depend on which two forecasts are compared, so explanations are cached by the time the
+// previous forecast was generated, the update time of the current one and the prompt version, together
+// with the provider that wrote them
+func (lh *LLMHandler) getForecastChangesExplanation(ctx context.Context, gridPoint nws.GridPoint, fcr generator.ForecastChangesResponse) (generator.ForecastChangesExplanation, error) {
+	cacheKey := lh.Cache.Key(
+		"forecast-changes-explanation",
+		gridPoint.String(),
+		strconv.FormatInt(fcr.PreviousGeneratedAt.Unix(), 10),
+		strconv.FormatInt(fcr.ForecastUpdateTime.Unix(), 10),
+		fcr.PromptVersion,
+	)
+
+	res, err := lh.Cache.Get(ctx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get forecast changes explanation from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		var fce generator.ForecastChangesExplanation
+		if err := json.Unmarshal(res, &fce); err == nil {
+			return fce, nil
+		}
+		slog.Error("could not unmarshal forecast changes explanation from cache", slog.String("key", cacheKey))
+	}
+
+	fce, err := lh.Generator.ExplainForecastChanges(ctx, generator.MaterialChanges(fcr.Changes))
+	if err != nil {
+		return generator.ForecastChangesExplanation{}, err
+	}
+
+	fceJSON, err := json.Marshal(fce)
+	if err != nil {
+		slog.Error("could not marshal forecast changes explanation for cache", slog.String("error", err.Error()))
+		return fce, nil
+	}
+
+	err = lh.Cache.Set(ctx, cacheKey, fceJSON, lh.Cache.CacheResultsDuration)
+	if err != nil {
+		slog.Error("could not set forecast changes explanation in cache", slog.String("error", err.Error()))
+	}
+
+	return fce, nil
+}
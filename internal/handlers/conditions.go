@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+type GetConditionsResponse struct {
+	nws.SimplifiedObservation
+	Comparison    string    `json:"comparison"`
+	LastUpdated   time.Time `json:"last_updated"`
+	Provider      string    `json:"provider"`
+	PromptVersion string    `json:"prompt_version"`
+}
+
+// conditions is what is cached for a grid point. The raw observation is kept rather than a simplified one, so
+// a single comparison serves every unit system
+type conditions struct {
+	Station       nws.Station             `json:"station"`
+	Observation   nws.ObservationResponse `json:"observation"`
+	Comparison    string                  `json:"comparison"`
+	LastUpdated   time.Time               `json:"last_updated"`
+	Provider      string                  `json:"provider"`
+	PromptVersion string                  `json:"prompt_version"`
+}
+
+// GetConditions returns the latest observation from the station nearest the grid point, along with a
+// one-line comparison against what the forecast predicted for the current period
+func (lh *LLMHandler) GetConditions(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	units, err := nws.ParseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		slog.Error("invalid units", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("invalid units"),
+			rfc9457.WithDetail(err.Error()),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	// the comparison quotes no numbers, so it is cached once and units are applied to the cached observation
+	cacheKey := lh.Cache.Key("conditions", gridPoint.String())
+
+	res, err := lh.Cache.Get(timeoutCtx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get conditions from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		var c conditions
+		err = json.Unmarshal(res, &c)
+		if err == nil {
+			lh.writeConditions(w, r, c, units)
+			return
+		}
+		slog.Error("could not unmarshal conditions from cache", slog.String("error", err.Error()))
+	}
+
+	station, err := lh.Resolver.NearestStation(timeoutCtx, gridPoint)
+	if err != nil {
+		slog.Error("failed to get nearest station", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get nearest station"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get nearest station: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	latestObservation, err := lh.NWSClient.GetLatestObservation(timeoutCtx, station.ID)
+	if err != nil {
+		slog.Error("failed to get latest observation", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get latest observation"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get latest observation: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	periods, err := lh.NWSClient.GetSimplifiedForecastNPeriods(timeoutCtx, gridPoint.String(), 1)
+	if err != nil {
+		slog.Error("failed to get current forecast period", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get current forecast period"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get current forecast period: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	if len(periods) == 0 {
+		slog.Error("forecast has no periods", slog.String("grid_point", gridPoint.String()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("forecast has no periods"),
+			rfc9457.WithDetail(fmt.Sprintf("forecast for %s has no periods", gridPoint.String())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	// the forecast is always in US units, so the observation is given to the LLM in US units too. The
+	// prompt keeps numbers out of the comparison, so it reads the same next to either unit system
+	comparisonObservation := nws.SimplifyObservation(latestObservation, station, nws.UnitsUS)
+
+	inputJSON, err := json.Marshal(struct {
+		Observed nws.SimplifiedObservation     `json:"observed"`
+		Forecast nws.SimplifiedForecastPeriods `json:"forecast"`
+	}{
+		Observed: comparisonObservation,
+		Forecast: periods[0],
+	})
+	if err != nil {
+		slog.Error("failed to marshal conditions input", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal conditions input"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal conditions input: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	prompt, err := lh.Prompts.Render(prompts.ConditionsComparison, prompts.Data{
+		Input: string(inputJSON),
+	})
+	if err != nil {
+		slog.Error("failed to render conditions prompt", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to render conditions prompt"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to render conditions prompt: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	response, err := lh.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    256,
+	})
+	if err != nil {
+		slog.Error("failed to get conditions comparison", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get conditions comparison"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get conditions comparison: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	c := conditions{
+		Station:       station,
+		Observation:   latestObservation,
+		Comparison:    strings.TrimSpace(generator.StripMarkdownCodeBlock(response.Content)),
+		LastUpdated:   time.Now(),
+		Provider:      response.Provider,
+		PromptVersion: prompt.Version,
+	}
+
+	cJson, err := json.Marshal(c)
+	if err != nil {
+		slog.Error("could not marshal conditions for cache", slog.String("error", err.Error()))
+	} else {
+		err = lh.Cache.Set(timeoutCtx, cacheKey, cJson, lh.ConditionsCacheDuration)
+		if err != nil {
+			slog.Error("could not set conditions in cache", slog.String("error", err.Error()))
+		}
+	}
+
+	lh.writeConditions(w, r, c, units)
+}
+
+// writeConditions writes conditions with the observation converted to units
+func (lh *LLMHandler) writeConditions(w http.ResponseWriter, r *http.Request, c conditions, units nws.Units) {
+	cResponse := GetConditionsResponse{
+		SimplifiedObservation: nws.SimplifyObservation(c.Observation, c.Station, units),
+		Comparison:            c.Comparison,
+		LastUpdated:           c.LastUpdated,
+		Provider:              c.Provider,
+		PromptVersion:         c.PromptVersion,
+	}
+
+	cJson, err := json.Marshal(cResponse)
+	if err != nil {
+		slog.Error("failed to marshal conditions", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal conditions"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal conditions: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(cJson)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+)
+
+type ForecastDiscussionInformation struct {
+	Explanation string `json:"explanation"`
+	Confidence  string `json:"confidence"`
+}
+
+var forecastDiscussionInformationSchema = &llm.JSONSchema{
+	Name:        "forecast_discussion_information",
+	Description: "A plain-language explanation of an Area Forecast Discussion and the forecaster's confidence",
+	Schema: generator.ObjectSchema(map[string]any{
+		"explanation": map[string]any{"type": "string"},
+		"confidence":  map[string]any{"type": "string", "enum": []string{"low", "moderate", "high"}},
+	}),
+}
+
+type GetForecastDiscussionResponse struct {
+	Office        string    `json:"office"`
+	ProductID     string    `json:"product_id"`
+	IssuanceTime  time.Time `json:"issuance_time"`
+	Explanation   string    `json:"explanation"`
+	Confidence    string    `json:"confidence"`
+	LastUpdated   time.Time `json:"last_updated"`
+	Provider      string    `json:"provider"`
+	PromptVersion string    `json:"prompt_version"`
+}
+
+// latestDiscussionCacheDuration is how long the ID of an office's latest discussion is reused before NWS is
+// asked again. Discussions are issued a few times a day, so a new one is picked up soon enough
+const latestDiscussionCacheDuration = 5 * time.Minute
+
+// latestDiscussionProductID returns the ID of the latest Area Forecast Discussion issued by office. The ID
+// is kept without expiry, so the last known discussion can still be served when NWS cannot list products
+func (lh *LLMHandler) latestDiscussionProductID(ctx context.Context, office string) (string, error) {
+	cacheKey := lh.Cache.Key("forecast-discussion-latest", office)
+
+	var lastKnownID string
+	res, err := lh.Cache.Get(ctx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get latest forecast discussion id from cache", slog.String("error", err.Error()))
+	} else if err == nil {
+		entry, err := cache.ParseEntry(res)
+		if err == nil {
+			err = json.Unmarshal(entry.Value, &lastKnownID)
+		}
+
+		if err != nil {
+			slog.Warn("ignoring invalid latest forecast discussion id in cache", slog.String("error", err.Error()))
+		} else if !entry.Stale() {
+			return lastKnownID, nil
+		}
+	}
+
+	productID, err := lh.NWSClient.GetLatestProductID(ctx, "AFD", office)
+	if err != nil {
+		if lastKnownID == "" {
+			return "", err
+		}
+
+		slog.Warn("could not get latest forecast discussion, using the last known one", slog.String("office", office), slog.String("product_id", lastKnownID), slog.String("error", err.Error()))
+		return lastKnownID, nil
+	}
+
+	// a string and an entry holding one always marshal
+	value, _ := json.Marshal(productID)
+	data, _ := cache.NewEntry(value, latestDiscussionCacheDuration).Marshal()
+	if err := lh.Cache.Set(ctx, cacheKey, data, 0); err != nil {
+		slog.Error("could not set latest forecast discussion id in cache", slog.String("error", err.Error()))
+	}
+
+	return productID, nil
+}
+
+// GetForecastDiscussion explains the forecaster's reasoning from the latest Area Forecast Discussion
+// issued by the grid point's forecast office
+func (lh *LLMHandler) GetForecastDiscussion(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	productID, err := lh.latestDiscussionProductID(timeoutCtx, gridPoint.Office)
+	if err != nil {
+		slog.Error("failed to get latest forecast discussion", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get latest forecast discussion"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get latest forecast discussion: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	// discussions are immutable once issued, so they are cached by product ID and the prompt version
+	cacheKey := lh.Cache.Key("forecast-discussion", productID, lh.Prompts.Version(prompts.ForecastDiscussion))
+
+	res, err := lh.Cache.Get(timeoutCtx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get forecast discussion from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		_, _ = w.Write(res)
+		return
+	}
+
+	product, err := lh.NWSClient.GetProduct(timeoutCtx, productID)
+	if err != nil {
+		slog.Error("failed to get forecast discussion", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get forecast discussion"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get forecast discussion: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	prompt, err := lh.Prompts.Render(prompts.ForecastDiscussion, prompts.Data{
+		Input: product.ProductText,
+	})
+	if err != nil {
+		slog.Error("failed to render forecast discussion prompt", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to render forecast discussion prompt"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to render forecast discussion prompt: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	response, err := lh.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
+		SystemPrompt: prompt.System,
+		UserPrompt:   prompt.User,
+		MaxTokens:    2048,
+		Schema:       forecastDiscussionInformationSchema,
+	})
+	if err != nil {
+		slog.Error("failed to get forecast discussion explanation", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get forecast discussion explanation"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get forecast discussion explanation: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	var fdi ForecastDiscussionInformation
+	cleanedText := generator.StripMarkdownCodeBlock(response.Content)
+	err = json.Unmarshal([]byte(cleanedText), &fdi)
+	if err != nil {
+		slog.Error("failed to unmarshal forecast discussion explanation", slog.String("error", err.Error()), slog.String("response", cleanedText))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to unmarshal forecast discussion explanation"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to unmarshal forecast discussion explanation: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	fdResponse := GetForecastDiscussionResponse{
+		Office:        gridPoint.Office,
+		ProductID:     product.ID,
+		IssuanceTime:  product.IssuanceTime,
+		Explanation:   fdi.Explanation,
+		Confidence:    fdi.Confidence,
+		LastUpdated:   time.Now(),
+		Provider:      response.Provider,
+		PromptVersion: prompt.Version,
+	}
+
+	fdJson, err := json.Marshal(fdResponse)
+	if err != nil {
+		slog.Error("failed to marshal forecast discussion", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal forecast discussion"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal forecast discussion: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	err = lh.Cache.Set(timeoutCtx, cacheKey, fdJson, lh.Cache.CacheResultsDuration)
+	if err != nil {
+		slog.Error("could not set forecast discussion in cache", slog.String("error", err.Error()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(fdJson)
+}
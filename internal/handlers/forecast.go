@@ -6,187 +6,126 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
-	"time"
 
 	"alpineworks.io/rfc9457"
-	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
-type ForecastHandler struct {
-	LLMHandler *LLMHandler
-	GridPoints string
-}
-
-type ForecastSummaryResponse struct {
-	Summary     string    `json:"summary"`
-	Icon        string    `json:"icon"`
-	LastUpdated time.Time `json:"last_updated"`
+// setCacheHeaders describes a cache entry's freshness to clients. Stale entries may still be reused for
+// as long as the cache keeps them while they are revalidated, but degraded responses should not be reused
+func (lh *LLMHandler) setCacheHeaders(w http.ResponseWriter, entry cache.Entry, degraded bool) {
+	if degraded {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", int(entry.MaxAge().Seconds()), int(lh.Cache.CacheStaleDuration.Seconds())))
+	}
+	w.Header().Set("Age", fmt.Sprintf("%d", int(entry.Age().Seconds())))
 }
 
-func (lh *LLMHandler) GetForecastSummary(w http.ResponseWriter, r *http.Request) {
-	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
-	defer cancel()
-
-	res, err := lh.DragonflyClient.Client.Get(timeoutCtx, fmt.Sprintf("%s-%s", lh.DragonflyClient.KeyPrefix, "forecast-summary")).Result()
-	if err != nil && err != redis.Nil {
-		slog.Error("could not get forecast summary from cache", slog.String("error", err.Error()))
-	} else if err == nil && res != "" {
-		var fsr ForecastSummaryResponse
-		err := json.Unmarshal([]byte(res), &fsr)
-		if err != nil {
-			slog.Error("could not unmarshal forecast summary from cache", slog.String("error", err.Error()))
-		}
-
-		fsrJson, err := json.Marshal(fsr)
-		if err != nil {
-			slog.Error("failed to marshal forecast summary from cache", slog.String("error", err.Error()))
-			rfc9457.NewRFC9457(
-				rfc9457.WithTitle("failed to marshal forecast summary from cache"),
-				rfc9457.WithDetail(fmt.Sprintf("failed to marshal forecast summary from cache: %s", err.Error())),
-				rfc9457.WithInstance(r.URL.Path),
-				rfc9457.WithStatus(http.StatusInternalServerError),
-			).ServeHTTP(w, r)
-			return
-		}
+// lastKnownGood returns the last successfully generated copy of a product after its generation failed,
+// reporting false if there is none
+func (lh *LLMHandler) lastKnownGood(ctx context.Context, key string, product string, generateErr error) (cache.Entry, bool) {
+	entry, err := lh.Coalescer.LastKnownGood(ctx, key)
+	if err != nil {
+		return cache.Entry{}, false
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+	slog.Warn("serving last known good forecast",
+		slog.String("product", product),
+		slog.String("key", key),
+		slog.Time("stored_at", entry.StoredAt),
+		slog.String("error", generateErr.Error()),
+	)
+	lh.fallbacksCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("product", product)))
 
-		_, _ = w.Write([]byte(fsrJson))
-		return
-	}
+	return entry, true
+}
 
-	periods, err := lh.NWSClient.GetSimplifiedForecastNPeriods("SEW/127,75", 3)
+// archive appends a generated product to the forecast history. Failing to archive should not fail the
+// request, so errors are only logged
+func (lh *LLMHandler) archive(ctx context.Context, gridPoint nws.GridPoint, product string, record history.Record) {
+	err := lh.History.Append(ctx, gridPoint, product, record)
 	if err != nil {
-		slog.Error("failed to get simplified forecast periods", slog.String("error", err.Error()))
-		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to get simplified forecast periods"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to get simplified forecast periods: %s", err.Error())),
-			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
-		).ServeHTTP(w, r)
-		return
+		slog.Error("could not archive forecast", slog.String("error", err.Error()), slog.String("grid_point", gridPoint.String()), slog.String("product", product))
 	}
+}
 
-	periodsJSON, err := json.Marshal(periods)
+func (lh *LLMHandler) GetForecastSummary(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
 	if err != nil {
-		slog.Error("failed to marshal simplified forecast periods", slog.String("error", err.Error()))
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
 		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to marshal simplified forecast periods"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to marshal simplified forecast periods: %s", err.Error())),
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
 			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
+			rfc9457.WithStatus(locationErrorStatus(err)),
 		).ServeHTTP(w, r)
 		return
 	}
 
-	systemPrompt := `You are a tool that can provide concise summaries of weather forecasts.
-	You have access to the following list of icons:
-	"""
-	cloud
-	cloud-drizzle
-	cloud-fog
-	cloud-hail
-	cloud-lightning
-	cloud-moon
-	cloud-moon-rain
-	cloud-rain
-	cloud-rain-wind
-	cloud-snow
-	cloud-sun
-	cloud-sun-rain
-	cloudy
-	snowflake
-	sun
-	sun-snow
-	thermometer-snowflake
-	thermometer-sun
-	wind
-	"""
-	`
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	key := lh.Cache.Key("forecast-summary", gridPoint.String())
 
-	prompt :=
-		`
-		Input is a JSON array with one entry per forecast period.
-		Output is a JSON object with the key "summary" containing the overall forecast in at most four sentences and "icon" containing the icon that best fits the soonest weather for this summary.
-		Each entry contains relavant weather information including a detailed text forecast.
-		Do not include any information that is not present in the input.
-		Do not comment twice on the same weather condition.
-		Focus mainly on the daytime periods.
-		Avoid editorializing or making assumptions.
-		Avoid referring to "periods" in the output.
-		Make the output sound like a human wrote it, with concise but friendly language and complete sentences.`
+	// stale entries are served immediately while they are refreshed, and concurrent misses for the same
+	// grid point wait on a single generation
+	entry, err := lh.Coalescer.Do(timeoutCtx, key, func(ctx context.Context) ([]byte, error) {
+		fsr, err := lh.Generator.GenerateForecastSummary(ctx, gridPoint)
+		if err != nil {
+			return nil, err
+		}
 
-	fewShotTraining := []MultiShot{
-		{
-			Input: `[
-					{
-						"name": "Tonight",
-						"start_time": "2024-06-08T20:00:00-07:00",
-						"end_time": "2024-06-09T06:00:00-07:00",
-						"temperature": "54F",
-						"detailed_forecast": "Mostly cloudy, with a low around 54. East wind around 2 mph.",
-						"relative_humidity": "80%",
-						"wind_speed": "2 mph E"
-						},
-					}
-					{
-						"name": "Sunday",
-						"start_time": "2024-06-09T06:00:00-07:00",
-						"end_time": "2024-06-09T18:00:00-07:00",
-						"temperature": "74F",
-						"detailed_forecast": "Mostly sunny. High near 74, with temperatures falling to around 72 in the afternoon. Southwest wind 1 to 6 mph.",
-						"relative_humidity": "79%",
-						"wind_speed": "1 to 6 mph SW"
-					},
-					{
-						"name": "Sunday Night",
-						"start_time": "2024-06-09T18:00:00-07:00",
-						"end_time": "2024-06-10T06:00:00-07:00",
-						"temperature": "51F",
-						"detailed_forecast": "Mostly cloudy, with a low around 51. West wind 2 to 6 mph.",
-						"relative_humidity": "85%",
-						"wind_speed": "2 to 6 mph W"
-					}
-				]`,
-			Output: `{"summary": "Tonight, mostly cloudy with a low around 54. Sunday, mostly sunny with a high near 74, temperatures falling to around 72 in the afternoon. Sunday night, mostly cloudy with a low around 51. Winds light and variable.", "icon": "cloud-moon"}`,
-		},
-	}
+		fsrJson, err := json.Marshal(fsr)
+		if err != nil {
+			return nil, err
+		}
 
-	response, err := lh.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
-		SystemPrompt: systemPrompt,
-		UserPrompt:   buildFinalPrompt(prompt, fewShotTraining, string(periodsJSON)),
-		MaxTokens:    4096,
+		lh.archive(ctx, gridPoint, history.ProductSummary, history.Record{
+			GeneratedAt:        fsr.LastUpdated,
+			ForecastUpdateTime: fsr.ForecastUpdateTime,
+			Forecast:           fsrJson,
+		})
+
+		return fsrJson, nil
 	})
+	var degraded bool
 	if err != nil {
-		slog.Error("failed to get forecast summary", slog.String("error", err.Error()))
-		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to get forecast summary"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to get forecast summary: %s", err.Error())),
-			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
-		).ServeHTTP(w, r)
-		return
+		// the request context is used since timeoutCtx may be what ran out
+		var ok bool
+		entry, ok = lh.lastKnownGood(r.Context(), key, "summary", err)
+		if !ok {
+			slog.Error("failed to generate forecast summary", slog.String("error", err.Error()))
+			rfc9457.NewRFC9457(
+				rfc9457.WithTitle("failed to generate forecast summary"),
+				rfc9457.WithDetail(fmt.Sprintf("failed to generate forecast summary: %s", err.Error())),
+				rfc9457.WithInstance(r.URL.Path),
+				rfc9457.WithStatus(http.StatusInternalServerError),
+			).ServeHTTP(w, r)
+			return
+		}
+		degraded = true
 	}
 
-	var fsr ForecastSummaryResponse
-	cleanedText := stripMarkdownCodeBlock(response.Content)
-	err = json.Unmarshal([]byte(cleanedText), &fsr)
+	var fsr generator.ForecastSummaryResponse
+	err = json.Unmarshal(entry.Value, &fsr)
 	if err != nil {
-		slog.Error("failed to unmarshal forecast summary", slog.String("error", err.Error()), slog.String("response", cleanedText))
+		slog.Error("could not unmarshal forecast summary", slog.String("error", err.Error()))
 		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to unmarshal forecast summary"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to unmarshal forecast summary: %s", err.Error())),
+			rfc9457.WithTitle("could not unmarshal forecast summary"),
+			rfc9457.WithDetail(fmt.Sprintf("could not unmarshal forecast summary: %s", err.Error())),
 			rfc9457.WithInstance(r.URL.Path),
 			rfc9457.WithStatus(http.StatusInternalServerError),
 		).ServeHTTP(w, r)
 		return
 	}
-
-	fsr.LastUpdated = time.Now()
+	fsr.Stale = entry.Stale()
+	fsr.Degraded = degraded
 
 	fsrJson, err := json.Marshal(fsr)
 	if err != nil {
@@ -200,248 +139,85 @@ func (lh *LLMHandler) GetForecastSummary(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = lh.DragonflyClient.Client.Set(timeoutCtx, fmt.Sprintf("%s-%s", lh.DragonflyClient.KeyPrefix, "forecast-summary"), fsrJson, lh.DragonflyClient.CacheResultsDuration).Err()
-	if err != nil {
-		slog.Error("could not set forecast summary in cache", slog.String("error", err.Error()))
-	}
-
+	lh.setCacheHeaders(w, entry, degraded)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(fsrJson))
-}
-
-type GetForecastPeriodsInformation struct {
-	Name      string `json:"name"`
-	TimeOfDay string `json:"time_of_day"`
-	Icon      string `json:"icon"`
-	Beaufort  string `json:"beaufort"`
-}
-
-type JoinedForecastPeriodsInformation struct {
-	Name             string    `json:"name"`
-	TimeOfDay        string    `json:"time_of_day"`
-	Icon             string    `json:"icon"`
-	Beaufort         string    `json:"beaufort"`
-	DetailedForecast string    `json:"detailed_forecast"`
-	ShortForecast    string    `json:"short_forecast"`
-	StartTime        time.Time `json:"start_time"`
-	EndTime          time.Time `json:"end_time"`
-	Temperature      int       `json:"temperature"`
-	WindSpeed        string    `json:"wind_speed"`
-	WindDirection    string    `json:"wind_direction"`
-}
-
-func JoinForecastPeriodsInformation(fpi GetForecastPeriodsInformation, period nws.SimplifiedForecastPeriods) JoinedForecastPeriodsInformation {
-	return JoinedForecastPeriodsInformation{
-		Name:             fpi.Name,
-		TimeOfDay:        fpi.TimeOfDay,
-		Icon:             fpi.Icon,
-		Beaufort:         fpi.Beaufort,
-		DetailedForecast: period.DetailedForecast,
-		ShortForecast:    period.ShortForecast,
-		StartTime:        period.StartTime,
-		EndTime:          period.EndTime,
-		Temperature:      period.Temperature,
-		WindSpeed:        period.WindSpeed,
-		WindDirection:    period.WindDirection,
-	}
-}
-
-type GetForecastPeriodsInformationResponse struct {
-	Periods     []JoinedForecastPeriodsInformation `json:"periods"`
-	LastUpdated time.Time                          `json:"last_updated"`
+	_, _ = w.Write(fsrJson)
 }
 
 func (lh *LLMHandler) GetForcastPeriodsInformation(w http.ResponseWriter, r *http.Request) {
-	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
-	defer cancel()
-
-	res, err := lh.DragonflyClient.Client.Get(timeoutCtx, fmt.Sprintf("%s-%s", lh.DragonflyClient.KeyPrefix, "forecast-periods-information")).Result()
-	if err != nil && err != redis.Nil {
-		slog.Error("could not get forecast periods information from cache", slog.String("error", err.Error()))
-	} else if err == nil && res != "" {
-		var fpi GetForecastPeriodsInformationResponse
-		err := json.Unmarshal([]byte(res), &fpi)
-		if err != nil {
-			slog.Error("could not unmarshal forecast periods information from cache", slog.String("error", err.Error()))
-		}
-
-		fpiJson, err := json.Marshal(fpi)
-		if err != nil {
-			slog.Error("failed to marshal forecast periods information from cache", slog.String("error", err.Error()))
-			rfc9457.NewRFC9457(
-				rfc9457.WithTitle("failed to marshal forecast periods information from cache"),
-				rfc9457.WithDetail(fmt.Sprintf("failed to marshal forecast periods information from cache: %s", err.Error())),
-				rfc9457.WithInstance(r.URL.Path),
-				rfc9457.WithStatus(http.StatusInternalServerError),
-			).ServeHTTP(w, r)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		_, _ = w.Write([]byte(fpiJson))
-		return
-	}
-
-	periods, err := lh.NWSClient.GetSimplifiedForecastNPeriods("SEW/127,75", -1)
+	gridPoint, err := lh.gridPointFromRequest(r)
 	if err != nil {
-		slog.Error("failed to get simplified forecast periods", slog.String("error", err.Error()))
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
 		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to get simplified forecast periods"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to get simplified forecast periods: %s", err.Error())),
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
 			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
-		).ServeHTTP(w, r)
-		return
-	}
-
-	periodsJSON, err := json.Marshal(periods)
-	if err != nil {
-		slog.Error("failed to marshal simplified forecast periods", slog.String("error", err.Error()))
-		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to marshal simplified forecast periods"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to marshal simplified forecast periods: %s", err.Error())),
-			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
+			rfc9457.WithStatus(locationErrorStatus(err)),
 		).ServeHTTP(w, r)
 		return
 	}
 
-	systemPrompt := `You are a tool that can provide concise weather forecast breakdowns.
-		You have access to the following list of icons:
-		"""
-		cloud
-		cloud-drizzle
-		cloud-fog
-		cloud-hail
-		cloud-lightning
-		cloud-moon
-		cloud-moon-rain
-		cloud-rain
-		cloud-rain-wind
-		cloud-snow
-		cloud-sun
-		cloud-sun-rain
-		cloudy
-		snowflake
-		sun
-		sun-snow
-		thermometer-snowflake
-		thermometer-sun
-		wind
-		"""
-		`
-
-	prompt :=
-		`Input is a JSON array with one entry per forecast period.
-		Output is a JSON array with the following key-value pairs:
-		"name": the "name" field on the given forecast period,
-		"time_of_day": either day or night based upon the given forecast period,
-		"icon": the icon that best fits the "detailed_forecast" for this forecast period,
-		"beaufort": the beaufort scale string that best fits the "wind_speed" for this period,
-
-		Do not include any information that is not present in the input.
-		Only include the JSON, do not include outside text.
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
 
+	key := lh.Cache.Key("forecast-periods-information", gridPoint.String())
 
-		Structure the output exactly like this, but remove all whitespace:
+	// stale entries are served immediately while they are refreshed, and concurrent misses for the same
+	// grid point wait on a single generation
+	entry, err := lh.Coalescer.Do(timeoutCtx, key, func(ctx context.Context) ([]byte, error) {
+		fpiResponse, err := lh.Generator.GenerateForecastPeriodsInformation(ctx, gridPoint)
+		if err != nil {
+			return nil, err
+		}
 
-		"""
-		[
-		{
-			"name": "",
-			"time_of_day": "",
-			"icon": "",
-			"beaufort": "",
-		},
-		...
-		]
-		"""
-		`
+		fpiJson, err := json.Marshal(fpiResponse)
+		if err != nil {
+			return nil, err
+		}
 
-	fewShotTraining := []MultiShot{
-		{
-			Input: `[
-					{
-						"name": "Tonight",
-						"start_time": "2024-06-08T20:00:00-07:00",
-						"end_time": "2024-06-09T06:00:00-07:00",
-						"temperature": "54F",
-						"detailed_forecast": "Mostly cloudy, with a low around 54. East wind around 2 mph.",
-						"relative_humidity": "80%",
-						"wind_speed": "2 mph E"
-						},
-					}
-					{
-						"name": "Sunday",
-						"start_time": "2024-06-09T06:00:00-07:00",
-						"end_time": "2024-06-09T18:00:00-07:00",
-						"temperature": "74F",
-						"detailed_forecast": "Mostly sunny. High near 74, with temperatures falling to around 72 in the afternoon. Southwest wind 1 to 6 mph.",
-						"relative_humidity": "79%",
-						"wind_speed": "1 to 6 mph SW"
-					},
-					{
-						"name": "Sunday Night",
-						"start_time": "2024-06-09T18:00:00-07:00",
-						"end_time": "2024-06-10T06:00:00-07:00",
-						"temperature": "51F",
-						"detailed_forecast": "Mostly cloudy, with a low around 51. West wind 2 to 6 mph.",
-						"relative_humidity": "85%",
-						"wind_speed": "2 to 6 mph W"
-					}
-				]`,
-			Output: `[{"name":"Tonight","time_of_day":"night","icon":"cloud-moon","beaufort":"Light air"},{"name":"Sunday","time_of_day":"day","icon":"cloud-sun","beaufort":"Light breeze"},{"name":"Sunday Night","time_of_day":"night","icon":"cloud-moon","beaufort":"Light breeze"}]`,
-		},
-	}
+		lh.archive(ctx, gridPoint, history.ProductDetailed, history.Record{
+			GeneratedAt:        fpiResponse.LastUpdated,
+			ForecastUpdateTime: fpiResponse.ForecastUpdateTime,
+			Forecast:           fpiJson,
+		})
 
-	response, err := lh.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
-		SystemPrompt: systemPrompt,
-		UserPrompt:   buildFinalPrompt(prompt, fewShotTraining, string(periodsJSON)),
-		MaxTokens:    4096,
+		return fpiJson, nil
 	})
+	var degraded bool
 	if err != nil {
-		slog.Error("failed to get forecast periods information", slog.String("error", err.Error()))
-		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to get forecast periods information"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to get forecast periods information: %s", err.Error())),
-			rfc9457.WithInstance(r.URL.Path),
-			rfc9457.WithStatus(http.StatusInternalServerError),
-		).ServeHTTP(w, r)
-		return
+		// the request context is used since timeoutCtx may be what ran out
+		var ok bool
+		entry, ok = lh.lastKnownGood(r.Context(), key, "detailed", err)
+		if !ok {
+			slog.Error("failed to generate forecast periods information", slog.String("error", err.Error()))
+			rfc9457.NewRFC9457(
+				rfc9457.WithTitle("failed to generate forecast periods information"),
+				rfc9457.WithDetail(fmt.Sprintf("failed to generate forecast periods information: %s", err.Error())),
+				rfc9457.WithInstance(r.URL.Path),
+				rfc9457.WithStatus(http.StatusInternalServerError),
+			).ServeHTTP(w, r)
+			return
+		}
+		degraded = true
 	}
 
-	var fpi []GetForecastPeriodsInformation
-	cleanedText := stripMarkdownCodeBlock(response.Content)
-	err = json.Unmarshal([]byte(cleanedText), &fpi)
+	var fpi generator.GetForecastPeriodsInformationResponse
+	err = json.Unmarshal(entry.Value, &fpi)
 	if err != nil {
-		slog.Error("failed to unmarshal forecast periods information", slog.String("error", err.Error()), slog.String("response", cleanedText))
+		slog.Error("could not unmarshal forecast periods information", slog.String("error", err.Error()))
 		rfc9457.NewRFC9457(
-			rfc9457.WithTitle("failed to unmarshal forecast periods information"),
-			rfc9457.WithDetail(fmt.Sprintf("failed to unmarshal forecast periods information: %s", err.Error())),
+			rfc9457.WithTitle("could not unmarshal forecast periods information"),
+			rfc9457.WithDetail(fmt.Sprintf("could not unmarshal forecast periods information: %s", err.Error())),
 			rfc9457.WithInstance(r.URL.Path),
 			rfc9457.WithStatus(http.StatusInternalServerError),
 		).ServeHTTP(w, r)
 		return
 	}
+	fpi.Stale = entry.Stale()
+	fpi.Degraded = degraded
 
-	joinedPeriods := make([]JoinedForecastPeriodsInformation, 0)
-	for _, period := range periods {
-		for _, fpiPeriod := range fpi {
-			if period.Name == fpiPeriod.Name {
-				joinedPeriods = append(joinedPeriods, JoinForecastPeriodsInformation(fpiPeriod, period))
-			}
-		}
-	}
-
-	fpiResponse := GetForecastPeriodsInformationResponse{
-		Periods:     joinedPeriods,
-		LastUpdated: time.Now(),
-	}
-
-	fpiJson, err := json.Marshal(fpiResponse)
+	fpiJson, err := json.Marshal(fpi)
 	if err != nil {
 		slog.Error("failed to marshal forecast periods information", slog.String("error", err.Error()))
 		rfc9457.NewRFC9457(
@@ -453,51 +229,8 @@ func (lh *LLMHandler) GetForcastPeriodsInformation(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = lh.DragonflyClient.Client.Set(timeoutCtx, fmt.Sprintf("%s-%s", lh.DragonflyClient.KeyPrefix, "forecast-periods-information"), fpiJson, lh.DragonflyClient.CacheResultsDuration).Err()
-	if err != nil {
-		slog.Error("could not set forecast periods information in cache", slog.String("error", err.Error()))
-	}
-
+	lh.setCacheHeaders(w, entry, degraded)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(fpiJson))
-}
-
-type MultiShot struct {
-	Input  string
-	Output string
-}
-
-func multiShotWrapper(ms []MultiShot) string {
-	var sb strings.Builder
-	sb.WriteString("<examples>")
-
-	for _, m := range ms {
-		sb.WriteString("<example>")
-		sb.WriteString("input: ")
-		sb.WriteString(m.Input)
-		sb.WriteString("\noutput: ")
-		sb.WriteString(m.Output)
-		sb.WriteString("</example>")
-	}
-
-	sb.WriteString("</examples>")
-	return sb.String()
-}
-
-func buildFinalPrompt(prompt string, ms []MultiShot, inputData string) string {
-	return fmt.Sprintf("%s\n\n%s\n\n%s", prompt, multiShotWrapper(ms), fmt.Sprintf("input: %s", inputData))
-}
-
-func stripMarkdownCodeBlock(text string) string {
-	// Remove ```json or ``` prefix and ``` suffix
-	text = strings.TrimSpace(text)
-	if strings.HasPrefix(text, "```json") {
-		text = strings.TrimPrefix(text, "```json")
-	} else if strings.HasPrefix(text, "```") {
-		text = strings.TrimPrefix(text, "```")
-	}
-	text = strings.TrimSpace(text)
-	text = strings.TrimSuffix(text, "```")
-	return strings.TrimSpace(text)
+	_, _ = w.Write(fpiJson)
 }
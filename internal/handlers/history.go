@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+const defaultHistoryWindow = 24 * time.Hour
+
+type GetForecastHistoryResponse struct {
+	GridPoint nws.GridPoint    `json:"grid_point"`
+	Product   string           `json:"product"`
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	History   []history.Record `json:"history"`
+}
+
+// historyRangeFromRequest parses the from and to query parameters as RFC 3339 times. to defaults to now
+// and from to defaultHistoryWindow before to
+func historyRangeFromRequest(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	to := time.Now()
+	if query.Has("to") {
+		var err error
+		to, err = time.Parse(time.RFC3339, query.Get("to"))
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be an RFC 3339 time: %w", err)
+		}
+	}
+
+	from := to.Add(-defaultHistoryWindow)
+	if query.Has("from") {
+		var err error
+		from, err = time.Parse(time.RFC3339, query.Get("from"))
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be an RFC 3339 time: %w", err)
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+
+	return from, to, nil
+}
+
+// productFromRequest parses the product query parameter, defaulting to the summary
+func productFromRequest(r *http.Request) (string, error) {
+	if !r.URL.Query().Has("product") {
+		return history.ProductSummary, nil
+	}
+
+	product := r.URL.Query().Get("product")
+	if !history.ValidProduct(product) {
+		return "", fmt.Errorf("product must be %s or %s", history.ProductSummary, history.ProductDetailed)
+	}
+
+	return product, nil
+}
+
+func (lh *LLMHandler) GetForecastHistory(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	from, to, err := historyRangeFromRequest(r)
+	if err != nil {
+		slog.Error("invalid history range", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("invalid history range"),
+			rfc9457.WithDetail(err.Error()),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	product, err := productFromRequest(r)
+	if err != nil {
+		slog.Error("invalid product", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("invalid product"),
+			rfc9457.WithDetail(err.Error()),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	records, err := lh.History.Range(timeoutCtx, gridPoint, product, from, to)
+	if err != nil {
+		slog.Error("failed to get forecast history", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to get forecast history"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to get forecast history: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	fhJson, err := json.Marshal(GetForecastHistoryResponse{
+		GridPoint: gridPoint,
+		Product:   product,
+		From:      from,
+		To:        to,
+		History:   records,
+	})
+	if err != nil {
+		slog.Error("failed to marshal forecast history", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal forecast history"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal forecast history: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(fhJson)
+}
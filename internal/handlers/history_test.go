@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+func TestHistoryRangeFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "from and to",
+			query:    "?from=2026-10-01T00:00:00Z&to=2026-10-02T12:00:00Z",
+			wantFrom: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "from defaults to a day before to",
+			query:    "?to=2026-10-02T12:00:00Z",
+			wantFrom: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "from equal to to",
+			query:    "?from=2026-10-02T12:00:00Z&to=2026-10-02T12:00:00Z",
+			wantFrom: time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "from after to",
+			query:   "?from=2026-10-03T00:00:00Z&to=2026-10-02T12:00:00Z",
+			wantErr: true,
+		},
+		{
+			name:    "invalid from",
+			query:   "?from=yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "invalid to",
+			query:   "?to=2026-10-02",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := historyRangeFromRequest(httptest.NewRequest(http.MethodGet, "/history"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("historyRangeFromRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("historyRangeFromRequest() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestHistoryRangeFromRequestDefaults(t *testing.T) {
+	before := time.Now()
+	from, to, err := historyRangeFromRequest(httptest.NewRequest(http.MethodGet, "/history", nil))
+	if err != nil {
+		t.Fatalf("historyRangeFromRequest() error = %v", err)
+	}
+
+	if to.Before(before) || to.After(time.Now()) {
+		t.Errorf("historyRangeFromRequest() to = %v, want now", to)
+	}
+	if to.Sub(from) != defaultHistoryWindow {
+		t.Errorf("historyRangeFromRequest() window = %v, want %v", to.Sub(from), defaultHistoryWindow)
+	}
+}
+
+func TestProductFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", query: "", want: history.ProductSummary},
+		{name: "summary", query: "?product=summary", want: history.ProductSummary},
+		{name: "detailed", query: "?product=detailed", want: history.ProductDetailed},
+		{name: "empty", query: "?product=", wantErr: true},
+		{name: "unknown", query: "?product=hourly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := productFromRequest(httptest.NewRequest(http.MethodGet, "/history"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("productFromRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("productFromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetForecastHistory(t *testing.T) {
+	ctx := context.Background()
+	generatedAt := time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC)
+	defaultGridPoint := nws.GridPoint{Office: "SEW", X: 124, Y: 69}
+	otherGridPoint := nws.GridPoint{Office: "PQR", X: 112, Y: 103}
+
+	// retention is relative to now, so it has to cover the fixed generation times
+	archive := history.NewArchive(cache.NewCache(cache.NewMemoryStore(10), time.Hour, time.Hour, "test"), 100*365*24*time.Hour)
+	appends := []struct {
+		gridPoint nws.GridPoint
+		product   string
+		at        time.Time
+	}{
+		{defaultGridPoint, history.ProductSummary, generatedAt.Add(-2 * time.Hour)},
+		{defaultGridPoint, history.ProductSummary, generatedAt},
+		{defaultGridPoint, history.ProductDetailed, generatedAt},
+		{otherGridPoint, history.ProductSummary, generatedAt},
+	}
+	for _, a := range appends {
+		err := archive.Append(ctx, a.gridPoint, a.product, history.Record{GeneratedAt: a.at, ForecastUpdateTime: a.at, Forecast: json.RawMessage(`{}`)})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	lh := &LLMHandler{
+		History:          archive,
+		Timeout:          time.Second,
+		DefaultGridPoint: defaultGridPoint,
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/history", lh.GetForecastHistory)
+	router.HandleFunc("/{office}/{x},{y}/history", lh.GetForecastHistory)
+
+	tests := []struct {
+		name          string
+		path          string
+		wantStatus    int
+		wantGridPoint nws.GridPoint
+		wantProduct   string
+		wantRecords   int
+	}{
+		{
+			name:          "default grid point",
+			path:          "/history?from=2026-10-02T00:00:00Z&to=2026-10-03T00:00:00Z",
+			wantStatus:    http.StatusOK,
+			wantGridPoint: defaultGridPoint,
+			wantProduct:   history.ProductSummary,
+			wantRecords:   2,
+		},
+		{
+			name:          "narrow window",
+			path:          "/history?from=2026-10-02T11:00:00Z&to=2026-10-03T00:00:00Z",
+			wantStatus:    http.StatusOK,
+			wantGridPoint: defaultGridPoint,
+			wantProduct:   history.ProductSummary,
+			wantRecords:   1,
+		},
+		{
+			name:          "detailed product",
+			path:          "/history?product=detailed&from=2026-10-02T00:00:00Z&to=2026-10-03T00:00:00Z",
+			wantStatus:    http.StatusOK,
+			wantGridPoint: defaultGridPoint,
+			wantProduct:   history.ProductDetailed,
+			wantRecords:   1,
+		},
+		{
+			name:          "grid point path",
+			path:          "/PQR/112,103/history?from=2026-10-02T00:00:00Z&to=2026-10-03T00:00:00Z",
+			wantStatus:    http.StatusOK,
+			wantGridPoint: otherGridPoint,
+			wantProduct:   history.ProductSummary,
+			wantRecords:   1,
+		},
+		{
+			name:       "invalid grid point",
+			path:       "/PQR/a,103/history",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid range",
+			path:       "/history?from=2026-10-03T00:00:00Z&to=2026-10-02T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid product",
+			path:       "/history?product=hourly",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("GetForecastHistory() status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response GetForecastHistoryResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+			if response.GridPoint != tt.wantGridPoint {
+				t.Errorf("GetForecastHistory() grid point = %v, want %v", response.GridPoint, tt.wantGridPoint)
+			}
+			if response.Product != tt.wantProduct {
+				t.Errorf("GetForecastHistory() product = %q, want %q", response.Product, tt.wantProduct)
+			}
+			if len(response.History) != tt.wantRecords {
+				t.Errorf("GetForecastHistory() returned %d records, want %d", len(response.History), tt.wantRecords)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+)
+
+const (
+	defaultHourlyHours = 12
+	maxHourlyHours     = 48
+)
+
+// hoursFromRequest parses the hours query parameter, defaulting to defaultHourlyHours
+func hoursFromRequest(r *http.Request) (int, error) {
+	if !r.URL.Query().Has("hours") {
+		return defaultHourlyHours, nil
+	}
+
+	hours, err := strconv.Atoi(r.URL.Query().Get("hours"))
+	if err != nil || hours < 1 || hours > maxHourlyHours {
+		return 0, fmt.Errorf("hours must be an integer between 1 and %d", maxHourlyHours)
+	}
+
+	return hours, nil
+}
+
+func (lh *LLMHandler) GetHourlyForecast(w http.ResponseWriter, r *http.Request) {
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		slog.Error("failed to determine grid point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to determine grid point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to determine grid point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	hours, err := hoursFromRequest(r)
+	if err != nil {
+		slog.Error("invalid hours", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("invalid hours"),
+			rfc9457.WithDetail(err.Error()),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(r.Context(), lh.Timeout)
+	defer cancel()
+
+	cacheKey := lh.Cache.Key("forecast-hourly", gridPoint.String(), strconv.Itoa(hours))
+
+	res, err := lh.Cache.Get(timeoutCtx, cacheKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get hourly forecast from cache", slog.String("error", err.Error()))
+	} else if err == nil && len(res) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		_, _ = w.Write(res)
+		return
+	}
+
+	hfResponse, err := lh.Generator.GenerateHourlyForecast(timeoutCtx, gridPoint, hours)
+	if err != nil {
+		slog.Error("failed to generate hourly forecast", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to generate hourly forecast"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to generate hourly forecast: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	hfJson, err := json.Marshal(hfResponse)
+	if err != nil {
+		slog.Error("failed to marshal hourly forecast", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal hourly forecast"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal hourly forecast: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	err = lh.Cache.Set(timeoutCtx, cacheKey, hfJson, lh.HourlyCacheDuration)
+	if err != nil {
+		slog.Error("could not set hourly forecast in cache", slog.String("error", err.Error()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(hfJson)
+}
@@ -1,25 +1,74 @@
 package handlers
 
 import (
+	"fmt"
 	"time"
 
-	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/dragonfly"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/coalesce"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
 )
 
+const meterName = "github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/handlers"
+
 type LLMHandler struct {
-	LLMProvider     llm.Provider
-	NWSClient       *nws.NWSClient
-	DragonflyClient *dragonfly.DragonflyClient
-	Timeout         time.Duration
+	LLMProvider      llm.Provider
+	Prompts          *prompts.Registry
+	Generator        *generator.Generator
+	Coalescer        *coalesce.Coalescer
+	NWSClient        *nws.NWSClient
+	Cache            *cache.Cache
+	History          *history.Archive
+	Resolver         *locations.Resolver
+	Registry         *locations.Registry
+	Timeout          time.Duration
+	DefaultGridPoint nws.GridPoint
+
+	HourlyCacheDuration     time.Duration
+	AlertsCacheDuration     time.Duration
+	ConditionsCacheDuration time.Duration
+
+	fallbacksCounter metric.Int64Counter
 }
 
-func NewLLMHandler(provider llm.Provider, nc *nws.NWSClient, dc *dragonfly.DragonflyClient, timeout time.Duration) *LLMHandler {
-	return &LLMHandler{
-		LLMProvider:     provider,
-		NWSClient:       nc,
-		DragonflyClient: dc,
-		Timeout:         timeout,
+func NewLLMHandler(provider llm.Provider, promptRegistry *prompts.Registry, nc *nws.NWSClient, c *cache.Cache, archive *history.Archive, resolver *locations.Resolver, registry *locations.Registry, timeout time.Duration, generationTimeout time.Duration, defaultGridPoint nws.GridPoint, hourlyCacheDuration time.Duration, alertsCacheDuration time.Duration, conditionsCacheDuration time.Duration) (*LLMHandler, error) {
+	fallbacksCounter, err := otel.Meter(meterName).Int64Counter(
+		"forecast.fallbacks",
+		metric.WithDescription("number of forecast products served from the last known good copy because generation failed, by product"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create fallbacks counter: %w", err)
+	}
+
+	gen, err := generator.NewGenerator(provider, promptRegistry, nc)
+	if err != nil {
+		return nil, err
 	}
+
+	return &LLMHandler{
+		LLMProvider:      provider,
+		Prompts:          promptRegistry,
+		Generator:        gen,
+		Coalescer:        coalesce.NewCoalescer(c, generationTimeout),
+		NWSClient:        nc,
+		Cache:            c,
+		History:          archive,
+		Resolver:         resolver,
+		Registry:         registry,
+		Timeout:          timeout,
+		DefaultGridPoint: defaultGridPoint,
+
+		HourlyCacheDuration:     hourlyCacheDuration,
+		AlertsCacheDuration:     alertsCacheDuration,
+		ConditionsCacheDuration: conditionsCacheDuration,
+
+		fallbacksCounter: fallbacksCounter,
+	}, nil
 }
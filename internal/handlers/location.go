@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"alpineworks.io/rfc9457"
+	"github.com/gorilla/mux"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+// gridPointFromRequest determines which grid point a request is for, in order of preference:
+// the {office}/{x},{y} path variables, the location query parameter, the lat and lon query parameters,
+// the place query parameter, then the default grid point
+func (lh *LLMHandler) gridPointFromRequest(r *http.Request) (nws.GridPoint, error) {
+	vars := mux.Vars(r)
+	if office, ok := vars["office"]; ok {
+		return nws.NewGridPoint(office, vars["x"], vars["y"])
+	}
+
+	query := r.URL.Query()
+	if query.Has("location") {
+		location, err := lh.Registry.Get(r.Context(), query.Get("location"))
+		if err != nil {
+			return nws.GridPoint{}, err
+		}
+
+		return location.GridPoint, nil
+	}
+
+	if query.Has("lat") || query.Has("lon") || query.Has("place") {
+		point, err := lh.pointFromQuery(r)
+		if err != nil {
+			return nws.GridPoint{}, err
+		}
+
+		return point.GridPoint, nil
+	}
+
+	return lh.DefaultGridPoint, nil
+}
+
+// pointFromQuery resolves the lat and lon, or place, query parameters to an NWS point
+func (lh *LLMHandler) pointFromQuery(r *http.Request) (nws.Point, error) {
+	query := r.URL.Query()
+	if !query.Has("lat") && !query.Has("lon") {
+		if !query.Has("place") {
+			return nws.Point{}, fmt.Errorf("%w: either lat and lon or place must be provided", nws.ErrInvalidCoordinates)
+		}
+
+		return lh.Resolver.ResolvePlace(r.Context(), query.Get("place"))
+	}
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil {
+		return nws.Point{}, fmt.Errorf("%w: lat %q is not a number", nws.ErrInvalidCoordinates, query.Get("lat"))
+	}
+
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil {
+		return nws.Point{}, fmt.Errorf("%w: lon %q is not a number", nws.ErrInvalidCoordinates, query.Get("lon"))
+	}
+
+	return lh.Resolver.Resolve(r.Context(), lat, lon)
+}
+
+// coordinatesFromRequest determines the coordinates a request is for, for NWS endpoints that only accept a point.
+// Grid points are converted to the center of their grid cell, which is cached so cached alerts do not
+// need an NWS request
+func (lh *LLMHandler) coordinatesFromRequest(r *http.Request) (float64, float64, error) {
+	query := r.URL.Query()
+	_, hasOffice := mux.Vars(r)["office"]
+	if !hasOffice && !query.Has("location") && (query.Has("lat") || query.Has("lon") || query.Has("place")) {
+		point, err := lh.pointFromQuery(r)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return point.Latitude, point.Longitude, nil
+	}
+
+	gridPoint, err := lh.gridPointFromRequest(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lh.Resolver.Centroid(r.Context(), gridPoint)
+}
+
+// locationErrorStatus maps a grid point resolution error to an HTTP status code
+func locationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, nws.ErrInvalidGridPoint), errors.Is(err, nws.ErrInvalidCoordinates), errors.Is(err, locations.ErrInvalidLocation):
+		return http.StatusBadRequest
+	case errors.Is(err, locations.ErrUnknownPlace), errors.Is(err, locations.ErrUnknownLocation), nws.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, locations.ErrStaticLocation), errors.Is(err, locations.ErrTooManyLocations):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (lh *LLMHandler) GetPoint(w http.ResponseWriter, r *http.Request) {
+	point, err := lh.pointFromQuery(r)
+	if err != nil {
+		slog.Error("failed to resolve point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to resolve point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to resolve point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	pointJSON, err := json.Marshal(PointResponse{
+		Point:     point,
+		PlaceName: point.PlaceName(),
+	})
+	if err != nil {
+		slog.Error("failed to marshal point", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal point"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal point: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pointJSON)
+}
+
+type PointResponse struct {
+	nws.Point
+	PlaceName string `json:"place_name"`
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"alpineworks.io/rfc9457"
+	"github.com/gorilla/mux"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
+)
+
+type ListLocationsResponse struct {
+	Locations []locations.Location `json:"locations"`
+}
+
+type PutLocationRequest struct {
+	GridPoint string `json:"grid_point"`
+}
+
+func (lh *LLMHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
+	locs, err := lh.Registry.List(r.Context())
+	if err != nil {
+		slog.Error("failed to list locations", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to list locations"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to list locations: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	locationsJSON, err := json.Marshal(ListLocationsResponse{Locations: locs})
+	if err != nil {
+		slog.Error("failed to marshal locations", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal locations"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal locations: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(locationsJSON)
+}
+
+func (lh *LLMHandler) PutLocation(w http.ResponseWriter, r *http.Request) {
+	var plr PutLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&plr); err != nil {
+		slog.Error("failed to decode location", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to decode location"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to decode location: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusBadRequest),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	location, err := locations.NewLocation(mux.Vars(r)["name"], plr.GridPoint)
+	if err == nil {
+		err = lh.Registry.Add(r.Context(), location)
+	}
+	if err != nil {
+		slog.Error("failed to add location", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to add location"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to add location: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	locationJSON, err := json.Marshal(location)
+	if err != nil {
+		slog.Error("failed to marshal location", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal location"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal location: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(locationJSON)
+}
+
+func (lh *LLMHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	err := lh.Registry.Remove(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		slog.Error("failed to remove location", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to remove location"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to remove location: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(locationErrorStatus(err)),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
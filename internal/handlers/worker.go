@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"alpineworks.io/rfc9457"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/worker"
+)
+
+type WorkerHandler struct {
+	Worker *worker.ForecastWorker
+}
+
+// NewWorkerHandler creates a handler reporting on the background worker, which is nil when the worker is disabled
+func NewWorkerHandler(forecastWorker *worker.ForecastWorker) *WorkerHandler {
+	return &WorkerHandler{
+		Worker: forecastWorker,
+	}
+}
+
+// GetStatus returns when the worker last ran and why, and when it will next run
+func (wh *WorkerHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if wh.Worker == nil {
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("worker disabled"),
+			rfc9457.WithDetail("the background worker is not enabled on this instance"),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusNotFound),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	statusJson, err := json.Marshal(wh.Worker.Status())
+	if err != nil {
+		slog.Error("failed to marshal worker status", slog.String("error", err.Error()))
+		rfc9457.NewRFC9457(
+			rfc9457.WithTitle("failed to marshal worker status"),
+			rfc9457.WithDetail(fmt.Sprintf("failed to marshal worker status: %s", err.Error())),
+			rfc9457.WithInstance(r.URL.Path),
+			rfc9457.WithStatus(http.StatusInternalServerError),
+		).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(statusJson)
+}
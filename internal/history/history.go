@@ -0,0 +1,92 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+// Products that are archived
+const (
+	ProductSummary  = "summary"
+	ProductDetailed = "detailed"
+)
+
+// Record is a single archived forecast product
+type Record struct {
+	GeneratedAt        time.Time       `json:"generated_at"`
+	ForecastUpdateTime time.Time       `json:"forecast_update_time"`
+	Forecast           json.RawMessage `json:"forecast"`
+}
+
+// Appender appends to a time-ordered log, such as the cache or a leader lease fencing the write
+type Appender interface {
+	Append(ctx context.Context, key string, t time.Time, value []byte, retention time.Duration) error
+}
+
+// Archive keeps every generated forecast product in a time-ordered log per grid point, so past
+// generations can be audited after the cached product has been overwritten
+type Archive struct {
+	Cache     *cache.Cache
+	Retention time.Duration
+}
+
+// NewArchive creates a new archive keeping records for retention
+func NewArchive(c *cache.Cache, retention time.Duration) *Archive {
+	return &Archive{
+		Cache:     c,
+		Retention: retention,
+	}
+}
+
+// ValidProduct reports whether product is archived
+func ValidProduct(product string) bool {
+	return product == ProductSummary || product == ProductDetailed
+}
+
+func (a *Archive) key(gridPoint nws.GridPoint, product string) string {
+	return a.Cache.Key("forecast-history", product, gridPoint.String())
+}
+
+// Append archives a generated forecast product
+func (a *Archive) Append(ctx context.Context, gridPoint nws.GridPoint, product string, record Record) error {
+	return a.AppendTo(ctx, a.Cache, gridPoint, product, record)
+}
+
+// AppendTo archives a generated forecast product through appender instead of writing to the cache directly
+func (a *Archive) AppendTo(ctx context.Context, appender Appender, gridPoint nws.GridPoint, product string, record Record) error {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("could not marshal history record: %w", err)
+	}
+
+	err = appender.Append(ctx, a.key(gridPoint, product), record.GeneratedAt, recordJSON, a.Retention)
+	if err != nil {
+		return fmt.Errorf("could not append history record: %w", err)
+	}
+
+	return nil
+}
+
+// Range returns the records of a product generated between from and to, oldest first
+func (a *Archive) Range(ctx context.Context, gridPoint nws.GridPoint, product string, from time.Time, to time.Time) ([]Record, error) {
+	values, err := a.Cache.Range(ctx, a.key(gridPoint, product), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not get history records: %w", err)
+	}
+
+	records := make([]Record, 0, len(values))
+	for _, value := range values {
+		var record Record
+		if err := json.Unmarshal(value, &record); err != nil {
+			return nil, fmt.Errorf("could not unmarshal history record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
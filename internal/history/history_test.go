@@ -0,0 +1,203 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+func newTestArchive(retention time.Duration) *Archive {
+	return NewArchive(cache.NewCache(cache.NewMemoryStore(10), time.Hour, time.Hour, "test"), retention)
+}
+
+func record(generatedAt time.Time, forecast string) Record {
+	forecastJSON, _ := json.Marshal(forecast)
+	return Record{
+		GeneratedAt:        generatedAt,
+		ForecastUpdateTime: generatedAt.Add(-time.Minute),
+		Forecast:           forecastJSON,
+	}
+}
+
+func forecasts(t *testing.T, records []Record) []string {
+	t.Helper()
+
+	var got []string
+	for _, record := range records {
+		var forecast string
+		if err := json.Unmarshal(record.Forecast, &forecast); err != nil {
+			t.Fatalf("could not unmarshal forecast: %v", err)
+		}
+		got = append(got, forecast)
+	}
+	return got
+}
+
+func TestArchiveRange(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	gridPoint := nws.GridPoint{Office: "SEW", X: 124, Y: 69}
+	otherGridPoint := nws.GridPoint{Office: "SEW", X: 125, Y: 69}
+
+	archive := newTestArchive(24 * time.Hour)
+	// appended out of order, so Range has to return them ordered by generation time
+	for _, r := range []Record{
+		record(now.Add(-1*time.Hour), "one hour ago"),
+		record(now.Add(-3*time.Hour), "three hours ago"),
+		record(now.Add(-2*time.Hour), "two hours ago"),
+	} {
+		if err := archive.Append(ctx, gridPoint, ProductSummary, r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if err := archive.Append(ctx, gridPoint, ProductDetailed, record(now.Add(-time.Hour), "detailed")); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := archive.Append(ctx, otherGridPoint, ProductSummary, record(now.Add(-time.Hour), "other grid point")); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		gridPoint nws.GridPoint
+		product   string
+		from      time.Time
+		to        time.Time
+		want      []string
+	}{
+		{
+			name:      "everything oldest first",
+			gridPoint: gridPoint,
+			product:   ProductSummary,
+			from:      now.Add(-24 * time.Hour),
+			to:        now,
+			want:      []string{"three hours ago", "two hours ago", "one hour ago"},
+		},
+		{
+			name:      "bounds are inclusive",
+			gridPoint: gridPoint,
+			product:   ProductSummary,
+			from:      now.Add(-3 * time.Hour),
+			to:        now.Add(-2 * time.Hour),
+			want:      []string{"three hours ago", "two hours ago"},
+		},
+		{
+			name:      "empty window",
+			gridPoint: gridPoint,
+			product:   ProductSummary,
+			from:      now.Add(-50 * time.Minute),
+			to:        now,
+		},
+		{
+			name:      "products are archived separately",
+			gridPoint: gridPoint,
+			product:   ProductDetailed,
+			from:      now.Add(-24 * time.Hour),
+			to:        now,
+			want:      []string{"detailed"},
+		},
+		{
+			name:      "grid points are archived separately",
+			gridPoint: otherGridPoint,
+			product:   ProductSummary,
+			from:      now.Add(-24 * time.Hour),
+			to:        now,
+			want:      []string{"other grid point"},
+		},
+		{
+			name:      "grid point without history",
+			gridPoint: nws.GridPoint{Office: "PQR", X: 1, Y: 1},
+			product:   ProductSummary,
+			from:      now.Add(-24 * time.Hour),
+			to:        now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := archive.Range(ctx, tt.gridPoint, tt.product, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Range() error = %v", err)
+			}
+
+			got := forecasts(t, records)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Range() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Range() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	gridPoint := nws.GridPoint{Office: "SEW", X: 124, Y: 69}
+
+	archive := newTestArchive(time.Hour)
+	for _, r := range []Record{
+		record(now.Add(-3*time.Hour), "expired"),
+		record(now.Add(-30*time.Minute), "retained"),
+		record(now, "latest"),
+	} {
+		if err := archive.Append(ctx, gridPoint, ProductSummary, r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	records, err := archive.Range(ctx, gridPoint, ProductSummary, now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+
+	got := forecasts(t, records)
+	if len(got) != 2 || got[0] != "retained" || got[1] != "latest" {
+		t.Errorf("Range() = %v, want [retained latest]", got)
+	}
+}
+
+type recordingAppender struct {
+	keys      []string
+	retention time.Duration
+}
+
+func (a *recordingAppender) Append(_ context.Context, key string, _ time.Time, _ []byte, retention time.Duration) error {
+	a.keys = append(a.keys, key)
+	a.retention = retention
+	return nil
+}
+
+func TestArchiveAppendTo(t *testing.T) {
+	gridPoint := nws.GridPoint{Office: "SEW", X: 124, Y: 69}
+	archive := newTestArchive(time.Hour)
+	appender := &recordingAppender{}
+
+	err := archive.AppendTo(context.Background(), appender, gridPoint, ProductDetailed, record(time.Now(), "fenced"))
+	if err != nil {
+		t.Fatalf("AppendTo() error = %v", err)
+	}
+
+	if len(appender.keys) != 1 || appender.keys[0] != archive.key(gridPoint, ProductDetailed) {
+		t.Errorf("AppendTo() appended to %v", appender.keys)
+	}
+	if appender.retention != time.Hour {
+		t.Errorf("AppendTo() retention = %v, want %v", appender.retention, time.Hour)
+	}
+
+	records, err := archive.Range(context.Background(), gridPoint, ProductDetailed, time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("AppendTo() wrote %d records to the cache, want 0", len(records))
+	}
+}
@@ -0,0 +1,272 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/dragonfly"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrLeaseLost            = errors.New("leader lease lost")
+	ErrInvalidLeaseDuration = errors.New("invalid lease duration")
+)
+
+// minLeaseDuration is the shortest lease allowed. The lease is stored with millisecond precision and renewed
+// every third of its duration, which must leave time for a round trip to dragonfly
+const minLeaseDuration = time.Second
+
+// acquireScript takes the lease if nobody holds it, returning a new fencing token or 0
+var acquireScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], ARGV[1] .. "/" .. token, "PX", ARGV[2])
+return token
+`)
+
+// renewScript extends the lease if it is still held with the given value
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lease if it is still held with the given value
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// fencedSetScript sets a key only if the lease is still held with the given value, so a leader that has
+// been replaced cannot overwrite the new leader's results. An expiration of 0 sets the key without one
+var fencedSetScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// fencedAppendScript appends to a time-ordered log only if the lease is still held with the given value,
+// like the cache's own Append
+var fencedAppendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", "(" .. ARGV[4])
+redis.call("PEXPIRE", KEYS[2], ARGV[5])
+return 1
+`)
+
+// Elector runs a function on exactly one replica at a time, using a lease key in dragonfly that the
+// leader renews. If the leader dies, the lease expires and another replica takes over
+type Elector struct {
+	DragonflyClient *dragonfly.DragonflyClient
+	Cache           *cache.Cache
+	Name            string
+	ID              string
+	LeaseDuration   time.Duration
+}
+
+// Lease is held by the current leader. Its fencing token increases with every election
+type Lease struct {
+	elector *Elector
+	Token   int64
+	value   string
+}
+
+type leaseContextKey struct{}
+
+// NewElector creates a leader elector for the named role. Election needs dragonfly since the lease is
+// shared between replicas, while c only provides the key prefix. Each replica is identified by its
+// hostname and a random suffix
+func NewElector(dragonflyClient *dragonfly.DragonflyClient, c *cache.Cache, name string, leaseDuration time.Duration) (*Elector, error) {
+	if leaseDuration < minLeaseDuration {
+		return nil, fmt.Errorf("%w: %s is shorter than the minimum of %s", ErrInvalidLeaseDuration, leaseDuration, minLeaseDuration)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("could not get hostname: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("could not generate elector id: %w", err)
+	}
+
+	return &Elector{
+		DragonflyClient: dragonflyClient,
+		Cache:           c,
+		Name:            name,
+		ID:              hostname + "-" + hex.EncodeToString(suffix),
+		LeaseDuration:   leaseDuration,
+	}, nil
+}
+
+func (e *Elector) leaseKey() string {
+	return e.Cache.Key("leader", e.Name)
+}
+
+func (e *Elector) tokenKey() string {
+	return e.Cache.Key("leader", e.Name, "token")
+}
+
+// Run campaigns for leadership until ctx is done, calling lead whenever this replica is elected. The
+// context passed to lead carries the Lease and is cancelled as soon as leadership is lost
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	renewInterval := e.LeaseDuration / 3
+
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		lease, err := e.acquire(ctx)
+		if err != nil {
+			slog.Error("could not acquire leader lease", slog.String("name", e.Name), slog.String("error", err.Error()))
+		} else if lease != nil {
+			slog.Info("elected leader", slog.String("name", e.Name), slog.String("id", e.ID), slog.Int64("token", lease.Token))
+			e.lead(ctx, lease, ticker, lead)
+			slog.Info("no longer leader", slog.String("name", e.Name), slog.String("id", e.ID), slog.Int64("token", lease.Token))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs lead while renewing the lease, returning once the lease is lost or ctx is done
+func (e *Elector) lead(ctx context.Context, lease *Lease, ticker *time.Ticker, lead func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(context.WithValue(ctx, leaseContextKey{}, lease))
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+	// the lease is considered lost once it may have expired without a successful renewal
+	deadline := time.Now().Add(e.LeaseDuration)
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			e.release(lease)
+			return
+		case <-done:
+			e.release(lease)
+			return
+		case <-ticker.C:
+			renewed, err := e.renew(ctx, lease)
+			if err != nil {
+				slog.Error("could not renew leader lease", slog.String("name", e.Name), slog.String("error", err.Error()))
+			}
+
+			if renewed {
+				deadline = time.Now().Add(e.LeaseDuration)
+			} else if err == nil || time.Now().After(deadline) {
+				cancel()
+				<-done
+				return
+			}
+		}
+	}
+}
+
+func (e *Elector) acquire(ctx context.Context) (*Lease, error) {
+	token, err := acquireScript.Run(ctx, e.DragonflyClient.Client, []string{e.leaseKey(), e.tokenKey()}, e.ID, e.LeaseDuration.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+
+	if token == 0 {
+		return nil, nil
+	}
+
+	return &Lease{
+		elector: e,
+		Token:   token,
+		value:   fmt.Sprintf("%s/%d", e.ID, token),
+	}, nil
+}
+
+func (e *Elector) renew(ctx context.Context, lease *Lease) (bool, error) {
+	renewed, err := renewScript.Run(ctx, e.DragonflyClient.Client, []string{e.leaseKey()}, lease.value, e.LeaseDuration.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return renewed == 1, nil
+}
+
+// release gives up the lease so another replica can take over without waiting for it to expire
+func (e *Elector) release(lease *Lease) {
+	releaseCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err := releaseScript.Run(releaseCtx, e.DragonflyClient.Client, []string{e.leaseKey()}, lease.value).Err()
+	if err != nil {
+		slog.Error("could not release leader lease", slog.String("name", e.Name), slog.String("error", err.Error()))
+	}
+}
+
+// Set writes a key only while this lease is still the current one, returning ErrLeaseLost otherwise
+func (l *Lease) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	set, err := fencedSetScript.Run(ctx, l.elector.DragonflyClient.Client, []string{l.elector.leaseKey(), key}, l.value, value, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+
+	if set == 0 {
+		return fmt.Errorf("%w: token %d", ErrLeaseLost, l.Token)
+	}
+
+	return nil
+}
+
+// Append adds to a time-ordered log only while this lease is still the current one, returning ErrLeaseLost
+// otherwise
+func (l *Lease) Append(ctx context.Context, key string, t time.Time, value []byte, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+
+	appended, err := fencedAppendScript.Run(ctx, l.elector.DragonflyClient.Client, []string{l.elector.leaseKey(), key}, l.value, value, t.UnixMilli(), cutoff.UnixMilli(), retention.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+
+	if appended == 0 {
+		return fmt.Errorf("%w: token %d", ErrLeaseLost, l.Token)
+	}
+
+	return nil
+}
+
+// LeaseFromContext returns the lease held by the leader running with ctx, or nil outside of leader election
+func LeaseFromContext(ctx context.Context) *Lease {
+	lease, _ := ctx.Value(leaseContextKey{}).(*Lease)
+	return lease
+}
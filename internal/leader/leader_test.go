@@ -0,0 +1,217 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/dragonfly"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestElectors returns electors for the same role on two replicas sharing mr
+func newTestElectors(t *testing.T, mr *miniredis.Miniredis, leaseDuration time.Duration) (*Elector, *Elector) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	dc := &dragonfly.DragonflyClient{Client: client}
+	c := cache.NewCache(cache.NewRedisStore(client), time.Hour, time.Hour, "test")
+
+	a, err := NewElector(dc, c, "worker", leaseDuration)
+	if err != nil {
+		t.Fatalf("NewElector() error = %v", err)
+	}
+
+	b, err := NewElector(dc, c, "worker", leaseDuration)
+	if err != nil {
+		t.Fatalf("NewElector() error = %v", err)
+	}
+
+	return a, b
+}
+
+func TestNewElectorLeaseDuration(t *testing.T) {
+	tests := []struct {
+		leaseDuration time.Duration
+		wantErr       error
+	}{
+		{leaseDuration: 0, wantErr: ErrInvalidLeaseDuration},
+		{leaseDuration: -time.Second, wantErr: ErrInvalidLeaseDuration},
+		{leaseDuration: 2 * time.Nanosecond, wantErr: ErrInvalidLeaseDuration},
+		{leaseDuration: 999 * time.Millisecond, wantErr: ErrInvalidLeaseDuration},
+		{leaseDuration: time.Second},
+		{leaseDuration: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		_, err := NewElector(&dragonfly.DragonflyClient{}, cache.NewCache(cache.NewMemoryStore(1), 0, 0, "test"), "worker", tt.leaseDuration)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewElector(%s) error = %v, want %v", tt.leaseDuration, err, tt.wantErr)
+		}
+	}
+}
+
+func TestElection(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	a, b := newTestElectors(t, mr, 30*time.Second)
+
+	leaseA, err := a.acquire(ctx)
+	if err != nil || leaseA == nil || leaseA.Token != 1 {
+		t.Fatalf("acquire() = %+v, %v, want token 1", leaseA, err)
+	}
+
+	if lease, err := b.acquire(ctx); err != nil || lease != nil {
+		t.Fatalf("acquire() while held = %+v, %v, want nil", lease, err)
+	}
+
+	if renewed, err := b.renew(ctx, &Lease{elector: b, Token: 1, value: b.ID + "/1"}); err != nil || renewed {
+		t.Errorf("renew() of a lease held by another replica = %v, %v, want false", renewed, err)
+	}
+
+	mr.FastForward(20 * time.Second)
+	if renewed, err := a.renew(ctx, leaseA); err != nil || !renewed {
+		t.Fatalf("renew() = %v, %v, want true", renewed, err)
+	}
+
+	// renewing restarted the lease, so it is still held after the original expiry
+	mr.FastForward(20 * time.Second)
+	if lease, err := b.acquire(ctx); err != nil || lease != nil {
+		t.Fatalf("acquire() after renewal = %+v, %v, want nil", lease, err)
+	}
+
+	a.release(leaseA)
+	leaseB, err := b.acquire(ctx)
+	if err != nil || leaseB == nil || leaseB.Token != 2 {
+		t.Fatalf("acquire() after release = %+v, %v, want token 2", leaseB, err)
+	}
+
+	// releasing a lease that has been taken over leaves the new leader's lease alone
+	a.release(leaseA)
+	if renewed, err := b.renew(ctx, leaseB); err != nil || !renewed {
+		t.Errorf("renew() after a stale release = %v, %v, want true", renewed, err)
+	}
+
+	mr.FastForward(31 * time.Second)
+	if renewed, err := b.renew(ctx, leaseB); err != nil || renewed {
+		t.Errorf("renew() of an expired lease = %v, %v, want false", renewed, err)
+	}
+
+	leaseA, err = a.acquire(ctx)
+	if err != nil || leaseA == nil || leaseA.Token != 3 {
+		t.Errorf("acquire() after expiry = %+v, %v, want token 3", leaseA, err)
+	}
+}
+
+func TestFencing(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	a, b := newTestElectors(t, mr, 30*time.Second)
+
+	leaseA, err := a.acquire(ctx)
+	if err != nil || leaseA == nil {
+		t.Fatalf("acquire() = %+v, %v", leaseA, err)
+	}
+
+	if err := leaseA.Set(ctx, "persistent", "a", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := leaseA.Set(ctx, "expiring", "a", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ttl := mr.TTL("persistent"); ttl != 0 {
+		t.Errorf("TTL of a key set without expiration = %s, want none", ttl)
+	}
+	if ttl := mr.TTL("expiring"); ttl != time.Minute {
+		t.Errorf("TTL of a key set with expiration = %s, want 1m", ttl)
+	}
+
+	now := time.Now()
+	if err := leaseA.Append(ctx, "log", now.Add(-2*time.Hour), []byte("old"), time.Hour); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := leaseA.Append(ctx, "log", now, []byte("a"), time.Hour); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if members, _ := mr.ZMembers("log"); len(members) != 1 || members[0] != "a" {
+		t.Errorf("log = %q, want entries older than retention trimmed", members)
+	}
+
+	// a paused leader whose lease expired is replaced
+	mr.FastForward(31 * time.Second)
+	leaseB, err := b.acquire(ctx)
+	if err != nil || leaseB == nil {
+		t.Fatalf("acquire() after expiry = %+v, %v", leaseB, err)
+	}
+
+	tests := []struct {
+		name    string
+		write   func() error
+		wantErr error
+	}{
+		{name: "replaced set", write: func() error { return leaseA.Set(ctx, "persistent", "stale", 0) }, wantErr: ErrLeaseLost},
+		{name: "replaced append", write: func() error { return leaseA.Append(ctx, "log", now, []byte("stale"), time.Hour) }, wantErr: ErrLeaseLost},
+		{name: "current set", write: func() error { return leaseB.Set(ctx, "expiring", "b", time.Minute) }},
+		{name: "current append", write: func() error { return leaseB.Append(ctx, "log", now.Add(time.Second), []byte("b"), time.Hour) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if value, _ := mr.Get("persistent"); value != "a" {
+		t.Errorf("persistent = %q, want the replaced leader's write rejected", value)
+	}
+	if value, _ := mr.Get("expiring"); value != "b" {
+		t.Errorf("expiring = %q, want the current leader's write", value)
+	}
+	if members, _ := mr.ZMembers("log"); len(members) != 2 || members[0] != "a" || members[1] != "b" {
+		t.Errorf("log = %q, want [a b]", members)
+	}
+}
+
+func TestRunCancelsLeaderOnLostLease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, _ := newTestElectors(t, mr, 30*time.Second)
+
+	// a short lease keeps the renewals quick, which NewElector would not allow
+	a.LeaseDuration = 30 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	elected := make(chan *Lease, 1)
+	stopped := make(chan struct{})
+	go a.Run(ctx, func(leaderCtx context.Context) {
+		elected <- LeaseFromContext(leaderCtx)
+		<-leaderCtx.Done()
+		close(stopped)
+	})
+
+	select {
+	case lease := <-elected:
+		if lease == nil {
+			t.Fatal("leader context has no lease")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not elected")
+	}
+
+	// another replica taking over the lease means the next renewal fails
+	mr.Set(a.leaseKey(), "other/99")
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("leader context was not cancelled after the lease was lost")
+	}
+}
@@ -25,16 +25,29 @@ func NewAnthropicProvider(apiKey string, model string) *AnthropicProvider {
 	}
 }
 
-// Complete sends a completion request to Anthropic's API
+// Complete sends a completion request to Anthropic's API. A schema is enforced by forcing the model to
+// call a tool whose input is the schema, and the tool input is returned as the content
 func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
-	message, err := p.client.Messages.New(ctx, anthropic.MessageNewParams{
+	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(p.model),
 		MaxTokens: req.MaxTokens,
 		System:    []anthropic.TextBlockParam{{Text: req.SystemPrompt}},
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(req.UserPrompt)),
 		},
-	})
+	}
+
+	if req.Schema != nil {
+		tool := anthropic.ToolUnionParamOfTool(toolInputSchema(req.Schema), req.Schema.Name)
+		if req.Schema.Description != "" {
+			tool.OfTool.Description = anthropic.String(req.Schema.Description)
+		}
+
+		params.Tools = []anthropic.ToolUnionParam{tool}
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(req.Schema.Name)
+	}
+
+	message, err := p.client.Messages.New(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic completion failed: %w", err)
 	}
@@ -43,11 +56,40 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest)
 		return nil, fmt.Errorf("anthropic returned empty response")
 	}
 
+	if req.Schema != nil {
+		for _, block := range message.Content {
+			if block.Type == "tool_use" && block.Name == req.Schema.Name {
+				return &CompletionResponse{
+					Content:  string(block.Input),
+					Provider: p.Name(),
+				}, nil
+			}
+		}
+
+		return nil, fmt.Errorf("anthropic response did not call the %s tool", req.Schema.Name)
+	}
+
 	return &CompletionResponse{
-		Content: message.Content[0].Text,
+		Content:  message.Content[0].Text,
+		Provider: p.Name(),
 	}, nil
 }
 
+// toolInputSchema converts a JSON schema into a tool input schema, which is always an object
+func toolInputSchema(schema *JSONSchema) anthropic.ToolInputSchemaParam {
+	extraFields := make(map[string]any, len(schema.Schema))
+	for k, v := range schema.Schema {
+		if k == "type" {
+			continue
+		}
+		extraFields[k] = v
+	}
+
+	return anthropic.ToolInputSchemaParam{
+		ExtraFields: extraFields,
+	}
+}
+
 // Name returns the provider name
 func (p *AnthropicProvider) Name() string {
 	return "anthropic"
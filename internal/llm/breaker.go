@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops requests to a provider after consecutive failures. Once the cooldown has passed a
+// single trial request is let through, closing the circuit again if it succeeds
+type circuitBreaker struct {
+	failureThreshold int
+	cooldown         time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(failureThreshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+// allow reports whether a request may be sent to the provider
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failureThreshold <= 0 || cb.failures < cb.failureThreshold {
+		return true
+	}
+
+	if time.Now().Before(cb.openUntil) || cb.probing {
+		return false
+	}
+
+	cb.probing = true
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.probing = false
+}
+
+func (cb *circuitBreaker) failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+	if cb.failureThreshold > 0 && cb.failures >= cb.failureThreshold {
+		cb.openUntil = time.Now().Add(cb.cooldown)
+	}
+}
+
+// abandon releases a trial request whose outcome says nothing about the provider, such as one cancelled
+// by the caller
+func (cb *circuitBreaker) abandon() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	// each step records an outcome, or with allow checks whether a request is let through
+	type step struct {
+		outcome string
+		allow   bool
+	}
+
+	tests := []struct {
+		name             string
+		failureThreshold int
+		cooldown         time.Duration
+		steps            []step
+	}{
+		{
+			name:             "closed below threshold",
+			failureThreshold: 3,
+			cooldown:         time.Hour,
+			steps:            []step{{outcome: "failure"}, {outcome: "failure"}, {outcome: "allow", allow: true}},
+		},
+		{
+			name:             "opens at threshold",
+			failureThreshold: 2,
+			cooldown:         time.Hour,
+			steps:            []step{{outcome: "failure"}, {outcome: "failure"}, {outcome: "allow", allow: false}},
+		},
+		{
+			name:             "success resets failures",
+			failureThreshold: 2,
+			cooldown:         time.Hour,
+			steps:            []step{{outcome: "failure"}, {outcome: "success"}, {outcome: "failure"}, {outcome: "allow", allow: true}},
+		},
+		{
+			name:             "disabled",
+			failureThreshold: 0,
+			cooldown:         time.Hour,
+			steps:            []step{{outcome: "failure"}, {outcome: "failure"}, {outcome: "failure"}, {outcome: "allow", allow: true}},
+		},
+		{
+			name:             "single trial after cooldown",
+			failureThreshold: 1,
+			cooldown:         0,
+			steps:            []step{{outcome: "failure"}, {outcome: "allow", allow: true}, {outcome: "allow", allow: false}},
+		},
+		{
+			name:             "successful trial closes",
+			failureThreshold: 1,
+			cooldown:         0,
+			steps:            []step{{outcome: "failure"}, {outcome: "allow", allow: true}, {outcome: "success"}, {outcome: "allow", allow: true}, {outcome: "allow", allow: true}},
+		},
+		{
+			name:             "abandoned trial lets another through",
+			failureThreshold: 1,
+			cooldown:         0,
+			steps:            []step{{outcome: "failure"}, {outcome: "allow", allow: true}, {outcome: "abandon"}, {outcome: "allow", allow: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := newCircuitBreaker(tt.failureThreshold, tt.cooldown)
+			for i, s := range tt.steps {
+				switch s.outcome {
+				case "success":
+					cb.success()
+				case "failure":
+					cb.failure()
+				case "abandon":
+					cb.abandon()
+				case "allow":
+					if got := cb.allow(); got != s.allow {
+						t.Errorf("step %d: allow() = %v, want %v", i, got, s.allow)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerCooldown(t *testing.T) {
+	cb := newCircuitBreaker(1, 20*time.Millisecond)
+	cb.failure()
+
+	if cb.allow() {
+		t.Fatal("allow() = true during cooldown, want false")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !cb.allow() {
+		t.Fatal("allow() = false after cooldown, want a trial request")
+	}
+
+	cb.failure()
+	if cb.allow() {
+		t.Fatal("allow() = true after a failed trial, want the cooldown to restart")
+	}
+}
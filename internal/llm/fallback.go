@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+
+var (
+	ErrCircuitOpen        = errors.New("circuit open")
+	ErrAllProvidersFailed = errors.New("all llm providers failed")
+)
+
+// FallbackTarget is a provider in a fallback chain and how long a single completion may take before the
+// next provider is tried. A zero timeout leaves only the caller's deadline
+type FallbackTarget struct {
+	Provider Provider
+	Timeout  time.Duration
+}
+
+type fallbackMember struct {
+	provider Provider
+	timeout  time.Duration
+	breaker  *circuitBreaker
+}
+
+// FallbackProvider implements the Provider interface by trying an ordered list of providers until one
+// succeeds. Each provider has its own circuit breaker, so a backend that is down is skipped until its
+// cooldown has passed instead of costing every request a timeout
+type FallbackProvider struct {
+	members []fallbackMember
+
+	completionsCounter metric.Int64Counter
+	durationHistogram  metric.Float64Histogram
+}
+
+// NewFallbackProvider creates a new fallback provider. A provider's circuit opens after failureThreshold
+// consecutive failures, or never when failureThreshold is 0, and stays open for cooldown
+func NewFallbackProvider(targets []FallbackTarget, failureThreshold int, cooldown time.Duration) (*FallbackProvider, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one llm provider is required")
+	}
+
+	completionsCounter, err := otel.Meter(meterName).Int64Counter(
+		"llm.completions",
+		metric.WithDescription("number of completion attempts, by provider and status"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create completions counter: %w", err)
+	}
+
+	durationHistogram, err := otel.Meter(meterName).Float64Histogram(
+		"llm.completion.duration",
+		metric.WithDescription("duration of completion attempts, by provider and status"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create completion duration histogram: %w", err)
+	}
+
+	members := make([]fallbackMember, 0, len(targets))
+	for _, target := range targets {
+		members = append(members, fallbackMember{
+			provider: target.Provider,
+			timeout:  target.Timeout,
+			breaker:  newCircuitBreaker(failureThreshold, cooldown),
+		})
+	}
+
+	return &FallbackProvider{
+		members:            members,
+		completionsCounter: completionsCounter,
+		durationHistogram:  durationHistogram,
+	}, nil
+}
+
+// Complete sends the completion request to each provider in order, returning the first successful
+// response with Provider set to the provider that served it
+func (p *FallbackProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	errs := make([]error, 0, len(p.members))
+	for i, member := range p.members {
+		name := member.provider.Name()
+
+		if !member.breaker.allow() {
+			p.record(ctx, name, "circuit_open", 0)
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrCircuitOpen))
+			continue
+		}
+
+		start := time.Now()
+		response, err := member.complete(ctx, req)
+		duration := time.Since(start)
+
+		if err == nil {
+			member.breaker.success()
+			p.record(ctx, name, "success", duration)
+			if i > 0 {
+				slog.Warn("llm completion served by fallback provider", slog.String("provider", name))
+			}
+
+			response.Provider = name
+			return response, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+
+		// the caller gave up, which says nothing about this provider and leaves no time for the next one
+		if ctx.Err() != nil {
+			member.breaker.abandon()
+			p.record(ctx, name, "cancelled", duration)
+			return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+		}
+
+		member.breaker.failure()
+		p.record(ctx, name, "failure", duration)
+		slog.Warn("llm provider failed", slog.String("provider", name), slog.String("error", err.Error()))
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+}
+
+func (m fallbackMember) complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	return m.provider.Complete(ctx, req)
+}
+
+func (p *FallbackProvider) record(ctx context.Context, provider string, status string, duration time.Duration) {
+	attributes := metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("status", status),
+	)
+
+	p.completionsCounter.Add(ctx, 1, attributes)
+	if status != "circuit_open" {
+		p.durationHistogram.Record(ctx, duration.Seconds(), attributes)
+	}
+}
+
+// Name returns the provider name
+func (p *FallbackProvider) Name() string {
+	return "fallback"
+}
@@ -8,19 +8,22 @@ import (
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 )
 
 var thinkTagRegexp = regexp.MustCompile(`(?s)<think>.*?</think>`)
 
 // OpenAIProvider implements the Provider interface for OpenAI-compatible APIs
 type OpenAIProvider struct {
-	client  *openai.Client
-	model   string
-	noThink bool
+	client           *openai.Client
+	model            string
+	noThink          bool
+	structuredOutput string
 }
 
-// NewOpenAIProvider creates a new OpenAI-compatible provider
-func NewOpenAIProvider(apiKey string, model string, baseURL string, noThink bool) *OpenAIProvider {
+// NewOpenAIProvider creates a new OpenAI-compatible provider. structuredOutput is one of the StructuredOutput
+// modes and decides how request schemas are sent
+func NewOpenAIProvider(apiKey string, model string, baseURL string, noThink bool, structuredOutput string) *OpenAIProvider {
 	opts := []option.RequestOption{
 		option.WithAPIKey(apiKey),
 	}
@@ -32,9 +35,10 @@ func NewOpenAIProvider(apiKey string, model string, baseURL string, noThink bool
 	client := openai.NewClient(opts...)
 
 	return &OpenAIProvider{
-		client:  &client,
-		model:   model,
-		noThink: noThink,
+		client:           &client,
+		model:            model,
+		noThink:          noThink,
+		structuredOutput: structuredOutput,
 	}
 }
 
@@ -52,11 +56,33 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*
 		}))
 	}
 
-	completion, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	params := openai.ChatCompletionNewParams{
 		Model:     p.model,
 		Messages:  messages,
 		MaxTokens: openai.Int(req.MaxTokens),
-	}, opts...)
+	}
+
+	if req.Schema != nil {
+		switch p.structuredOutput {
+		case StructuredOutputJSONSchema:
+			jsonSchema := shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   req.Schema.Name,
+				Strict: openai.Bool(true),
+				Schema: req.Schema.Schema,
+			}
+			if req.Schema.Description != "" {
+				jsonSchema.Description = openai.String(req.Schema.Description)
+			}
+
+			params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{JSONSchema: jsonSchema},
+			}
+		case StructuredOutputGrammar:
+			opts = append(opts, option.WithJSONSet("json_schema", req.Schema.Schema))
+		}
+	}
+
+	completion, err := p.client.Chat.Completions.New(ctx, params, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("openai completion failed: %w", err)
 	}
@@ -69,7 +95,8 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*
 	content = stripThinkTags(content)
 
 	return &CompletionResponse{
-		Content: content,
+		Content:  content,
+		Provider: p.Name(),
 	}, nil
 }
 
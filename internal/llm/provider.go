@@ -10,11 +10,15 @@ type CompletionRequest struct {
 	UserPrompt   string
 	MaxTokens    int64
 	NoThink      bool
+	// Schema optionally constrains the response content to a JSON object
+	Schema *JSONSchema
 }
 
 // CompletionResponse represents a response from an LLM provider
 type CompletionResponse struct {
 	Content string
+	// Provider is the name of the provider that served the completion
+	Provider string
 }
 
 // Provider defines the interface for LLM providers
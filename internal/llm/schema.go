@@ -0,0 +1,23 @@
+package llm
+
+// JSONSchema constrains a completion to a JSON object matching Schema. Providers that support structured
+// output guarantee a schema-valid response, others only receive the prompt's own instructions
+type JSONSchema struct {
+	// Name identifies the schema to the provider, e.g. as the name of the tool the response is given to
+	Name        string
+	Description string
+	// Schema is a JSON schema with type "object", written for OpenAI strict mode: every property is
+	// required and additionalProperties is false
+	Schema map[string]any
+}
+
+// Structured output modes for OpenAI-compatible APIs
+const (
+	// StructuredOutputJSONSchema uses the response_format json_schema parameter
+	StructuredOutputJSONSchema = "json_schema"
+	// StructuredOutputGrammar uses the json_schema parameter of llama.cpp-compatible servers, which
+	// constrains sampling with a grammar generated from the schema
+	StructuredOutputGrammar = "grammar"
+	// StructuredOutputNone ignores schemas, for servers that support neither
+	StructuredOutputNone = "none"
+)
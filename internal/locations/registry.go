@@ -0,0 +1,208 @@
+package locations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+var (
+	ErrInvalidLocation   = errors.New("invalid location")
+	ErrUnknownLocation   = errors.New("unknown location")
+	ErrStaticLocation    = errors.New("location is configured statically")
+	ErrDuplicateLocation = errors.New("duplicate location")
+	ErrTooManyLocations  = errors.New("too many locations")
+)
+
+// Location is a named grid point that the worker generates forecasts for
+type Location struct {
+	Name      string        `json:"name"`
+	GridPoint nws.GridPoint `json:"grid_point"`
+	Static    bool          `json:"static"`
+}
+
+// ParseLocation parses a location in the "{name}={office}/{x},{y}" form, e.g. home=SEW/127,75
+func ParseLocation(s string) (Location, error) {
+	name, gridPoint, ok := strings.Cut(s, "=")
+	if !ok {
+		return Location{}, fmt.Errorf("%w: %q is not in the form name=office/x,y", ErrInvalidLocation, s)
+	}
+
+	return NewLocation(name, gridPoint)
+}
+
+// NewLocation builds and validates a location from a name and a grid point string
+func NewLocation(name string, gridPoint string) (Location, error) {
+	normalizedName := nws.NormalizePlaceName(name)
+	if normalizedName == "" {
+		return Location{}, fmt.Errorf("%w: name %q must contain at least one letter or digit", ErrInvalidLocation, name)
+	}
+
+	gp, err := nws.ParseGridPoint(gridPoint)
+	if err != nil {
+		return Location{}, fmt.Errorf("%w: %w", ErrInvalidLocation, err)
+	}
+
+	return Location{
+		Name:      normalizedName,
+		GridPoint: gp,
+	}, nil
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s=%s", l.Name, l.GridPoint.String())
+}
+
+// Registry holds the set of locations forecasts are generated for. Locations come
+// from configuration and from a hash in the cache, so new locations can be added at runtime
+type Registry struct {
+	Cache           *cache.Cache
+	StaticLocations []Location
+
+	// MaxDynamicLocations caps the locations added at runtime, as every location adds LLM and NWS calls
+	// to each worker run
+	MaxDynamicLocations int
+}
+
+// NewRegistry creates a new location registry
+func NewRegistry(c *cache.Cache, staticLocations []Location, maxDynamicLocations int) (*Registry, error) {
+	seen := make(map[string]struct{}, len(staticLocations))
+	for i := range staticLocations {
+		if _, ok := seen[staticLocations[i].Name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateLocation, staticLocations[i].Name)
+		}
+		seen[staticLocations[i].Name] = struct{}{}
+		staticLocations[i].Static = true
+	}
+
+	return &Registry{
+		Cache:               c,
+		StaticLocations:     staticLocations,
+		MaxDynamicLocations: maxDynamicLocations,
+	}, nil
+}
+
+// key returns the key of the hash dynamic locations are stored in, with each location's grid point
+// stored under its name
+func (r *Registry) key() string {
+	return r.Cache.Key("locations")
+}
+
+// List returns every registered location sorted by name. Static locations take
+// precedence over dynamic locations with the same name. If the cache is unavailable
+// only the static locations are returned
+func (r *Registry) List(ctx context.Context) ([]Location, error) {
+	locations := make(map[string]Location, len(r.StaticLocations))
+	for _, location := range r.StaticLocations {
+		locations[location.Name] = location
+	}
+
+	dynamicLocations, err := r.dynamicLocations(ctx)
+	if err != nil {
+		slog.Error("could not list dynamic locations, using static locations only", slog.String("error", err.Error()))
+	}
+
+	for _, location := range dynamicLocations {
+		if _, ok := locations[location.Name]; ok {
+			continue
+		}
+
+		locations[location.Name] = location
+	}
+
+	result := make([]Location, 0, len(locations))
+	for _, location := range locations {
+		result = append(result, location)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// dynamicLocations returns the locations added at runtime
+func (r *Registry) dynamicLocations(ctx context.Context) ([]Location, error) {
+	fields, err := r.Cache.GetFields(ctx, r.key())
+	if err != nil {
+		return nil, fmt.Errorf("could not list locations: %w", err)
+	}
+
+	locations := make([]Location, 0, len(fields))
+	for name, gridPoint := range fields {
+		location, err := NewLocation(name, string(gridPoint))
+		if err != nil {
+			slog.Warn("skipping invalid location in registry", slog.String("name", name), slog.String("grid_point", string(gridPoint)), slog.String("error", err.Error()))
+			continue
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+// Get returns the location with the given name
+func (r *Registry) Get(ctx context.Context, name string) (Location, error) {
+	normalizedName := nws.NormalizePlaceName(name)
+	for _, location := range r.StaticLocations {
+		if location.Name == normalizedName {
+			return location, nil
+		}
+	}
+
+	gridPoint, err := r.Cache.GetField(ctx, r.key(), normalizedName)
+	if errors.Is(err, cache.ErrNotFound) {
+		return Location{}, fmt.Errorf("%w: %s", ErrUnknownLocation, normalizedName)
+	} else if err != nil {
+		return Location{}, fmt.Errorf("could not get location: %w", err)
+	}
+
+	return NewLocation(normalizedName, string(gridPoint))
+}
+
+// Add registers a location in the cache, replacing any dynamic location with the same name
+func (r *Registry) Add(ctx context.Context, location Location) error {
+	for _, static := range r.StaticLocations {
+		if static.Name == location.Name {
+			return fmt.Errorf("%w: %s", ErrStaticLocation, location.Name)
+		}
+	}
+
+	// replacing a location does not add to the count, which is checked atomically with the write so
+	// concurrent adds cannot go over the cap
+	added, err := r.Cache.SetField(ctx, r.key(), location.Name, []byte(location.GridPoint.String()), r.MaxDynamicLocations)
+	if err != nil {
+		return fmt.Errorf("could not add location: %w", err)
+	} else if !added {
+		return fmt.Errorf("%w: at most %d locations can be added at runtime", ErrTooManyLocations, r.MaxDynamicLocations)
+	}
+
+	return nil
+}
+
+// Remove deletes a dynamic location from the cache
+func (r *Registry) Remove(ctx context.Context, name string) error {
+	normalizedName := nws.NormalizePlaceName(name)
+	for _, location := range r.StaticLocations {
+		if location.Name == normalizedName {
+			return fmt.Errorf("%w: %s", ErrStaticLocation, normalizedName)
+		}
+	}
+
+	removed, err := r.Cache.DeleteField(ctx, r.key(), normalizedName)
+	if err != nil {
+		return fmt.Errorf("could not remove location: %w", err)
+	} else if !removed {
+		return fmt.Errorf("%w: %s", ErrUnknownLocation, normalizedName)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package locations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+func newTestRegistry(t *testing.T, maxDynamicLocations int) *Registry {
+	t.Helper()
+
+	static, err := ParseLocation("home=SEW/127,75")
+	if err != nil {
+		t.Fatalf("ParseLocation() error = %v", err)
+	}
+
+	r, err := NewRegistry(cache.NewCache(cache.NewMemoryStore(10), time.Hour, time.Hour, "test"), []Location{static}, maxDynamicLocations)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRegistry(t, 2)
+
+	tests := []struct {
+		name     string
+		location string
+		wantErr  error
+	}{
+		{name: "add", location: "cabin=OTX/10,20"},
+		{name: "static", location: "home=SEW/1,1", wantErr: ErrStaticLocation},
+		{name: "add second", location: "beach=PQR/30,40"},
+		{name: "over the cap", location: "lake=SEW/5,5", wantErr: ErrTooManyLocations},
+		{name: "replace at the cap", location: "cabin=OTX/11,21"},
+	}
+
+	for _, tt := range tests {
+		location, err := ParseLocation(tt.location)
+		if err != nil {
+			t.Fatalf("ParseLocation(%q) error = %v", tt.location, err)
+		}
+
+		if err := r.Add(ctx, location); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Add(%q) error = %v, want %v", tt.name, tt.location, err, tt.wantErr)
+		}
+	}
+
+	cabin, err := r.Get(ctx, "Cabin")
+	if err != nil || cabin.GridPoint != (nws.GridPoint{Office: "OTX", X: 11, Y: 21}) || cabin.Static {
+		t.Errorf("Get(cabin) = %+v, %v, want the replaced dynamic location", cabin, err)
+	}
+
+	home, err := r.Get(ctx, "home")
+	if err != nil || !home.Static {
+		t.Errorf("Get(home) = %+v, %v, want the static location", home, err)
+	}
+
+	if _, err := r.Get(ctx, "lake"); !errors.Is(err, ErrUnknownLocation) {
+		t.Errorf("Get(lake) error = %v, want ErrUnknownLocation", err)
+	}
+
+	locations, err := r.List(ctx)
+	if err != nil || len(locations) != 3 || locations[0].Name != "beach" || locations[1].Name != "cabin" || locations[2].Name != "home" {
+		t.Errorf("List() = %+v, %v, want beach, cabin and home", locations, err)
+	}
+
+	if err := r.Remove(ctx, "home"); !errors.Is(err, ErrStaticLocation) {
+		t.Errorf("Remove(home) error = %v, want ErrStaticLocation", err)
+	}
+	if err := r.Remove(ctx, "cabin"); err != nil {
+		t.Errorf("Remove(cabin) error = %v", err)
+	}
+	if err := r.Remove(ctx, "cabin"); !errors.Is(err, ErrUnknownLocation) {
+		t.Errorf("Remove(cabin) twice error = %v, want ErrUnknownLocation", err)
+	}
+}
+
+func TestRegistryConcurrentAdds(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRegistry(t, 3)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- r.Add(ctx, Location{Name: fmt.Sprintf("location%d", i), GridPoint: nws.GridPoint{Office: "SEW", X: i, Y: i}})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		if err == nil {
+			added++
+		} else if !errors.Is(err, ErrTooManyLocations) {
+			t.Errorf("Add() error = %v, want nil or ErrTooManyLocations", err)
+		}
+	}
+
+	if added != 3 {
+		t.Errorf("%d concurrent adds succeeded, want 3", added)
+	}
+}
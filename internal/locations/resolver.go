@@ -0,0 +1,178 @@
+package locations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+var (
+	ErrUnknownPlace = errors.New("unknown place")
+)
+
+// Resolver resolves coordinates and saved place names to NWS points, caching
+// the mapping since grid assignments rarely change
+type Resolver struct {
+	NWSClient     *nws.NWSClient
+	Cache         *cache.Cache
+	CacheDuration time.Duration
+}
+
+// NewResolver creates a new location resolver
+func NewResolver(nwsClient *nws.NWSClient, c *cache.Cache, cacheDuration time.Duration) *Resolver {
+	return &Resolver{
+		NWSClient:     nwsClient,
+		Cache:         c,
+		CacheDuration: cacheDuration,
+	}
+}
+
+// Resolve returns the point covering the given coordinates, saving it under its place name as well
+func (r *Resolver) Resolve(ctx context.Context, lat float64, lon float64) (nws.Point, error) {
+	if err := nws.ValidateCoordinates(lat, lon); err != nil {
+		return nws.Point{}, err
+	}
+
+	pointKey := r.Cache.Key("point", fmt.Sprintf("%.4f,%.4f", lat, lon))
+
+	point, err := r.getPoint(ctx, pointKey)
+	if err == nil {
+		return point, nil
+	} else if !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get point from cache", slog.String("error", err.Error()))
+	}
+
+	point, err = r.NWSClient.ResolvePoint(ctx, lat, lon)
+	if err != nil {
+		return nws.Point{}, err
+	}
+
+	r.setPoint(ctx, pointKey, point)
+	if placeName := point.PlaceName(); placeName != "" {
+		r.setPoint(ctx, r.Cache.Key("place", placeName), point)
+	}
+
+	return point, nil
+}
+
+// ResolvePlace returns the point previously saved under the given place name
+func (r *Resolver) ResolvePlace(ctx context.Context, name string) (nws.Point, error) {
+	placeName := nws.NormalizePlaceName(name)
+
+	point, err := r.getPoint(ctx, r.Cache.Key("place", placeName))
+	if errors.Is(err, cache.ErrNotFound) {
+		return nws.Point{}, fmt.Errorf("%w: %s", ErrUnknownPlace, placeName)
+	} else if err != nil {
+		return nws.Point{}, err
+	}
+
+	return point, nil
+}
+
+// NearestStation returns the observation station closest to a grid point
+func (r *Resolver) NearestStation(ctx context.Context, gridPoint nws.GridPoint) (nws.Station, error) {
+	stationKey := r.Cache.Key("station", gridPoint.String())
+
+	res, err := r.Cache.Get(ctx, stationKey)
+	if err == nil {
+		var station nws.Station
+		if err := json.Unmarshal(res, &station); err == nil {
+			return station, nil
+		}
+		slog.Error("could not unmarshal station from cache", slog.String("key", stationKey))
+	} else if !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get station from cache", slog.String("error", err.Error()))
+	}
+
+	station, err := r.NWSClient.GetNearestStation(ctx, gridPoint.String())
+	if err != nil {
+		return nws.Station{}, err
+	}
+
+	stationJSON, err := json.Marshal(station)
+	if err != nil {
+		slog.Error("could not marshal station", slog.String("error", err.Error()))
+		return station, nil
+	}
+
+	err = r.Cache.Set(ctx, stationKey, stationJSON, r.CacheDuration)
+	if err != nil {
+		slog.Error("could not set station in cache", slog.String("error", err.Error()), slog.String("key", stationKey))
+	}
+
+	return station, nil
+}
+
+// centroid is the center of a grid cell
+type centroid struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Centroid returns the approximate center of a grid point's cell. Finding it takes a full forecast fetch,
+// so it is cached like points
+func (r *Resolver) Centroid(ctx context.Context, gridPoint nws.GridPoint) (float64, float64, error) {
+	centroidKey := r.Cache.Key("centroid", gridPoint.String())
+
+	res, err := r.Cache.Get(ctx, centroidKey)
+	if err == nil {
+		var c centroid
+		if err := json.Unmarshal(res, &c); err == nil {
+			return c.Latitude, c.Longitude, nil
+		}
+		slog.Error("could not unmarshal centroid from cache", slog.String("key", centroidKey))
+	} else if !errors.Is(err, cache.ErrNotFound) {
+		slog.Error("could not get centroid from cache", slog.String("error", err.Error()))
+	}
+
+	lat, lon, err := r.NWSClient.GetGridPointCentroid(ctx, gridPoint.String())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	centroidJSON, err := json.Marshal(centroid{Latitude: lat, Longitude: lon})
+	if err != nil {
+		slog.Error("could not marshal centroid", slog.String("error", err.Error()))
+		return lat, lon, nil
+	}
+
+	err = r.Cache.Set(ctx, centroidKey, centroidJSON, r.CacheDuration)
+	if err != nil {
+		slog.Error("could not set centroid in cache", slog.String("error", err.Error()), slog.String("key", centroidKey))
+	}
+
+	return lat, lon, nil
+}
+
+func (r *Resolver) getPoint(ctx context.Context, key string) (nws.Point, error) {
+	res, err := r.Cache.Get(ctx, key)
+	if err != nil {
+		return nws.Point{}, err
+	}
+
+	var point nws.Point
+	if err := json.Unmarshal(res, &point); err != nil {
+		return nws.Point{}, fmt.Errorf("could not unmarshal point from cache: %w", err)
+	}
+
+	return point, nil
+}
+
+func (r *Resolver) setPoint(ctx context.Context, key string, point nws.Point) {
+	pointJSON, err := json.Marshal(point)
+	if err != nil {
+		slog.Error("could not marshal point", slog.String("error", err.Error()))
+		return
+	}
+
+	err = r.Cache.Set(ctx, key, pointJSON, r.CacheDuration)
+	if err != nil {
+		slog.Error("could not set point in cache", slog.String("error", err.Error()), slog.String("key", key))
+	}
+}
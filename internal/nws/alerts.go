@@ -0,0 +1,179 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type AlertsResponse struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Updated  string `json:"updated"`
+	Features []struct {
+		ID         string `json:"id"`
+		Properties struct {
+			ID          string     `json:"id"`
+			AreaDesc    string     `json:"areaDesc"`
+			Sent        time.Time  `json:"sent"`
+			Effective   time.Time  `json:"effective"`
+			Onset       *time.Time `json:"onset"`
+			Expires     time.Time  `json:"expires"`
+			Ends        *time.Time `json:"ends"`
+			Status      string     `json:"status"`
+			MessageType string     `json:"messageType"`
+			Category    string     `json:"category"`
+			Severity    string     `json:"severity"`
+			Certainty   string     `json:"certainty"`
+			Urgency     string     `json:"urgency"`
+			Event       string     `json:"event"`
+			SenderName  string     `json:"senderName"`
+			Headline    string     `json:"headline"`
+			Description string     `json:"description"`
+			Instruction string     `json:"instruction"`
+			Response    string     `json:"response"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+type SimplifiedAlert struct {
+	ID          string     `json:"id"`
+	Event       string     `json:"event"`
+	Headline    string     `json:"headline"`
+	Severity    string     `json:"severity"`
+	Urgency     string     `json:"urgency"`
+	Certainty   string     `json:"certainty"`
+	MessageType string     `json:"message_type"`
+	AreaDesc    string     `json:"area_desc"`
+	SenderName  string     `json:"sender_name"`
+	Effective   time.Time  `json:"effective"`
+	Onset       *time.Time `json:"onset,omitempty"`
+	Expires     time.Time  `json:"expires"`
+	Ends        *time.Time `json:"ends,omitempty"`
+	Description string     `json:"description"`
+	Instruction string     `json:"instruction"`
+}
+
+func (nc *NWSClient) GetActiveAlerts(ctx context.Context, lat float64, lon float64) (AlertsResponse, error) {
+	if err := ValidateCoordinates(lat, lon); err != nil {
+		return AlertsResponse{}, err
+	}
+
+	alertsURL := fmt.Sprintf("https://api.weather.gov/alerts/active?point=%.4f,%.4f", lat, lon)
+	slog.Info("getting active alerts", slog.String("url", alertsURL))
+	resp, err := nc.get(ctx, alertsURL)
+	if err != nil {
+		slog.Error("could not get active alerts", slog.String("error", err.Error()))
+		return AlertsResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var alerts AlertsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+		slog.Error("could not decode active alerts", slog.String("error", err.Error()))
+		return AlertsResponse{}, err
+	}
+
+	return alerts, nil
+}
+
+func (nc *NWSClient) GetSimplifiedActiveAlerts(ctx context.Context, lat float64, lon float64) ([]SimplifiedAlert, error) {
+	alerts, err := nc.GetActiveAlerts(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	return alertsResponseToSimplifiedAlerts(alerts), nil
+}
+
+func alertsResponseToSimplifiedAlerts(alerts AlertsResponse) []SimplifiedAlert {
+	simplifiedAlerts := make([]SimplifiedAlert, 0, len(alerts.Features))
+	for _, feature := range alerts.Features {
+		simplifiedAlerts = append(simplifiedAlerts, SimplifiedAlert{
+			ID:          feature.Properties.ID,
+			Event:       feature.Properties.Event,
+			Headline:    feature.Properties.Headline,
+			Severity:    feature.Properties.Severity,
+			Urgency:     feature.Properties.Urgency,
+			Certainty:   feature.Properties.Certainty,
+			MessageType: feature.Properties.MessageType,
+			AreaDesc:    feature.Properties.AreaDesc,
+			SenderName:  feature.Properties.SenderName,
+			Effective:   feature.Properties.Effective,
+			Onset:       feature.Properties.Onset,
+			Expires:     feature.Properties.Expires,
+			Ends:        feature.Properties.Ends,
+			Description: feature.Properties.Description,
+			Instruction: feature.Properties.Instruction,
+		})
+	}
+	return simplifiedAlerts
+}
+
+// GetGridPointCentroid returns the approximate center of a grid cell, for NWS endpoints that only accept coordinates
+func (nc *NWSClient) GetGridPointCentroid(ctx context.Context, gridpoints string) (float64, float64, error) {
+	forecast, err := nc.GetForecast(ctx, gridpoints)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return forecast.Centroid()
+}
+
+// Centroid returns the approximate center of the forecast's grid cell
+func (f ForecastResponse) Centroid() (float64, float64, error) {
+	if len(f.Geometry.Coordinates) == 0 || len(f.Geometry.Coordinates[0]) == 0 {
+		return 0, 0, fmt.Errorf("forecast has no geometry")
+	}
+
+	// GeoJSON polygons repeat the first vertex at the end of the ring
+	ring := f.Geometry.Coordinates[0]
+	if len(ring) > 1 {
+		ring = ring[:len(ring)-1]
+	}
+
+	var lat, lon float64
+	for _, vertex := range ring {
+		if len(vertex) < 2 {
+			return 0, 0, fmt.Errorf("forecast has malformed geometry")
+		}
+		lon += vertex[0]
+		lat += vertex[1]
+	}
+
+	return lat / float64(len(ring)), lon / float64(len(ring)), nil
+}
+
+// severityRanks orders CAP severities from least to most severe
+var severityRanks = map[string]int{
+	"unknown":  0,
+	"minor":    1,
+	"moderate": 2,
+	"severe":   3,
+	"extreme":  4,
+}
+
+// IsWarning reports whether the alert is a warning, the most serious NWS alert level, e.g. "Wind Warning"
+func (a SimplifiedAlert) IsWarning() bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(a.Event)), "warning")
+}
+
+// MaxSeverity returns the lowercased CAP severity of the most severe alert, or "none" when there are no alerts
+func MaxSeverity(alerts []SimplifiedAlert) string {
+	if len(alerts) == 0 {
+		return "none"
+	}
+
+	maxSeverity := "unknown"
+	for _, alert := range alerts {
+		severity := strings.ToLower(alert.Severity)
+		if severityRanks[severity] > severityRanks[maxSeverity] {
+			maxSeverity = severity
+		}
+	}
+
+	return maxSeverity
+}
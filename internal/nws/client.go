@@ -0,0 +1,177 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ProblemError is an NWS API error response, decoded from its application/problem+json body when present
+type ProblemError struct {
+	Status        int    `json:"status"`
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Detail        string `json:"detail"`
+	Instance      string `json:"instance"`
+	CorrelationID string `json:"correlationId"`
+
+	// RetryAfter is how long the Retry-After header asked clients to wait, 0 when it was not sent
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *ProblemError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("nws api returned %d %s: %s", e.Status, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("nws api returned %d %s", e.Status, e.Title)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *ProblemError) Temporary() bool {
+	return e.Status >= http.StatusInternalServerError || e.Status == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether err is an NWS 404, e.g. for coordinates outside the NWS coverage area
+func IsNotFound(err error) bool {
+	var problem *ProblemError
+	return errors.As(err, &problem) && problem.Status == http.StatusNotFound
+}
+
+// get performs a GET request against the NWS API, retrying server errors and timeouts with jittered
+// exponential backoff, or after the Retry-After delay when NWS sends one. Retries stop once ctx is done.
+// Non-2xx responses are returned as a *ProblemError
+func (nc *NWSClient) get(ctx context.Context, url string) (*http.Response, error) {
+	return nc.getWithHeaders(ctx, url, nil)
+}
+
+// getWithHeaders is get with additional request headers. A 304 Not Modified response to a conditional
+// request is returned as a response rather than an error
+func (nc *NWSClient) getWithHeaders(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+	var err error
+	for attempt := 0; attempt <= nc.maxRetries; attempt++ {
+		if attempt > 0 {
+			if wait(ctx, nc.retryDelay(attempt, err)) != nil {
+				return nil, err
+			}
+			slog.Warn("retrying nws request", slog.String("url", url), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+		}
+
+		var resp *http.Response
+		resp, err = nc.do(ctx, url, headers)
+		if err == nil {
+			return resp, nil
+		}
+
+		// a cancelled request also looks like a timeout, but retrying it cannot succeed
+		if ctx.Err() != nil || !retryable(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// retryDelay returns how long to wait before a retry, following Retry-After when the failed response had one
+func (nc *NWSClient) retryDelay(attempt int, err error) time.Duration {
+	var problem *ProblemError
+	if errors.As(err, &problem) && problem.RetryAfter > 0 {
+		return problem.RetryAfter
+	}
+
+	// full jitter keeps replicas from retrying in lockstep
+	if backoff := nc.retryBackoff << (attempt - 1); backoff > 0 {
+		return rand.N(backoff)
+	}
+	return 0
+}
+
+// wait sleeps for d, returning early with the context's error once ctx is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date, returning 0 when
+// it is missing or invalid
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+func (nc *NWSClient) do(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("User-Agent", nc.userAgent)
+	req.Header.Set("Accept", "application/geo+json")
+
+	resp, err := nc.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if (resp.StatusCode >= 200 && resp.StatusCode < 300) || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	problem := &ProblemError{}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err == nil {
+		_ = json.Unmarshal(body, problem)
+	}
+
+	// the body may be missing or not problem+json, so the status line is authoritative
+	problem.Status = resp.StatusCode
+	if problem.Title == "" {
+		problem.Title = http.StatusText(resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		problem.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+
+	return nil, problem
+}
+
+// retryable reports whether a failed request should be retried
+func retryable(err error) bool {
+	var problem *ProblemError
+	if errors.As(err, &problem) {
+		return problem.Temporary()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
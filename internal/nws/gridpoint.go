@@ -0,0 +1,94 @@
+package nws
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidGridPoint   = errors.New("invalid grid point")
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+)
+
+var officeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// GridPoint identifies a single NWS forecast grid cell, e.g. SEW/127,75
+type GridPoint struct {
+	Office string `json:"office"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+}
+
+// NewGridPoint builds and validates a grid point from its raw components
+func NewGridPoint(office string, x string, y string) (GridPoint, error) {
+	gx, err := strconv.Atoi(strings.TrimSpace(x))
+	if err != nil {
+		return GridPoint{}, fmt.Errorf("%w: x %q is not an integer", ErrInvalidGridPoint, x)
+	}
+
+	gy, err := strconv.Atoi(strings.TrimSpace(y))
+	if err != nil {
+		return GridPoint{}, fmt.Errorf("%w: y %q is not an integer", ErrInvalidGridPoint, y)
+	}
+
+	gp := GridPoint{
+		Office: strings.ToUpper(strings.TrimSpace(office)),
+		X:      gx,
+		Y:      gy,
+	}
+
+	if err := gp.Validate(); err != nil {
+		return GridPoint{}, err
+	}
+
+	return gp, nil
+}
+
+// ParseGridPoint parses a grid point in the "{office}/{x},{y}" form used by the NWS API
+func ParseGridPoint(s string) (GridPoint, error) {
+	office, coordinates, ok := strings.Cut(s, "/")
+	if !ok {
+		return GridPoint{}, fmt.Errorf("%w: %q is not in the form office/x,y", ErrInvalidGridPoint, s)
+	}
+
+	x, y, ok := strings.Cut(coordinates, ",")
+	if !ok {
+		return GridPoint{}, fmt.Errorf("%w: %q is not in the form office/x,y", ErrInvalidGridPoint, s)
+	}
+
+	return NewGridPoint(office, x, y)
+}
+
+// Validate checks that the grid point is well formed
+func (gp GridPoint) Validate() error {
+	if !officeRegexp.MatchString(gp.Office) {
+		return fmt.Errorf("%w: office %q must be a three letter forecast office identifier", ErrInvalidGridPoint, gp.Office)
+	}
+
+	if gp.X < 0 || gp.Y < 0 {
+		return fmt.Errorf("%w: coordinates %d,%d must not be negative", ErrInvalidGridPoint, gp.X, gp.Y)
+	}
+
+	return nil
+}
+
+// String returns the grid point in the "{office}/{x},{y}" form used by the NWS API
+func (gp GridPoint) String() string {
+	return fmt.Sprintf("%s/%d,%d", gp.Office, gp.X, gp.Y)
+}
+
+// ValidateCoordinates checks that a latitude and longitude are within range
+func ValidateCoordinates(lat float64, lon float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("%w: latitude %f must be between -90 and 90", ErrInvalidCoordinates, lat)
+	}
+
+	if lon < -180 || lon > 180 {
+		return fmt.Errorf("%w: longitude %f must be between -180 and 180", ErrInvalidCoordinates, lon)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GridpointSeries is a raw forecast time series. Each value applies to the ISO-8601 interval in its
+// ValidTime, e.g. "2024-06-08T18:00:00+00:00/PT3H"
+type GridpointSeries struct {
+	UOM    string `json:"uom"`
+	Values []struct {
+		ValidTime string   `json:"validTime"`
+		Value     *float64 `json:"value"`
+	} `json:"values"`
+}
+
+type GridpointDataResponse struct {
+	Properties struct {
+		UpdateTime                 time.Time       `json:"updateTime"`
+		ValidTimes                 string          `json:"validTimes"`
+		Dewpoint                   GridpointSeries `json:"dewpoint"`
+		SkyCover                   GridpointSeries `json:"skyCover"`
+		WindGust                   GridpointSeries `json:"windGust"`
+		ProbabilityOfPrecipitation GridpointSeries `json:"probabilityOfPrecipitation"`
+		QuantitativePrecipitation  GridpointSeries `json:"quantitativePrecipitation"`
+		SnowfallAmount             GridpointSeries `json:"snowfallAmount"`
+	} `json:"properties"`
+}
+
+// Interval is a single value of a parsed GridpointSeries
+type Interval struct {
+	Start time.Time
+	End   time.Time
+	Value float64
+}
+
+func (nc *NWSClient) GetGridpointData(ctx context.Context, gridpoints string) (GridpointDataResponse, error) {
+	gridpointURL := "https://api.weather.gov/gridpoints/" + gridpoints
+	slog.Info("getting gridpoint data", slog.String("url", gridpointURL))
+	resp, err := nc.get(ctx, gridpointURL)
+	if err != nil {
+		slog.Error("could not get gridpoint data", slog.String("error", err.Error()))
+		return GridpointDataResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var data GridpointDataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		slog.Error("could not decode gridpoint data", slog.String("error", err.Error()))
+		return GridpointDataResponse{}, err
+	}
+
+	return data, nil
+}
+
+// Intervals parses the series into intervals, skipping values that are null or have an unparseable valid time
+func (s GridpointSeries) Intervals() []Interval {
+	intervals := make([]Interval, 0, len(s.Values))
+	for _, v := range s.Values {
+		if v.Value == nil {
+			continue
+		}
+
+		start, end, err := ParseValidTime(v.ValidTime)
+		if err != nil {
+			slog.Warn("skipping gridpoint value", slog.String("valid_time", v.ValidTime), slog.String("error", err.Error()))
+			continue
+		}
+
+		intervals = append(intervals, Interval{Start: start, End: end, Value: *v.Value})
+	}
+	return intervals
+}
+
+// Sum returns the total of an accumulated quantity such as precipitation between start and end. Intervals
+// that only partially overlap contribute in proportion to the overlap. ok is false when no interval overlaps
+func (s GridpointSeries) Sum(start time.Time, end time.Time) (float64, bool) {
+	var sum float64
+	var found bool
+	for _, interval := range s.Intervals() {
+		overlap := overlapFraction(interval, start, end)
+		if overlap == 0 {
+			continue
+		}
+		sum += interval.Value * overlap
+		found = true
+	}
+	return sum, found
+}
+
+// Max returns the largest value of an instantaneous quantity such as wind gust between start and end.
+// ok is false when no interval overlaps
+func (s GridpointSeries) Max(start time.Time, end time.Time) (float64, bool) {
+	maxValue := math.Inf(-1)
+	var found bool
+	for _, interval := range s.Intervals() {
+		if overlapFraction(interval, start, end) == 0 {
+			continue
+		}
+		maxValue = math.Max(maxValue, interval.Value)
+		found = true
+	}
+	return maxValue, found
+}
+
+// Mean returns the time-weighted average of a quantity such as sky cover between start and end.
+// ok is false when no interval overlaps
+func (s GridpointSeries) Mean(start time.Time, end time.Time) (float64, bool) {
+	var weighted float64
+	var total time.Duration
+	for _, interval := range s.Intervals() {
+		overlap := overlapDuration(interval, start, end)
+		if overlap <= 0 {
+			continue
+		}
+		weighted += interval.Value * overlap.Seconds()
+		total += overlap
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return weighted / total.Seconds(), true
+}
+
+// overlapDuration returns how much of the interval falls between start and end
+func overlapDuration(interval Interval, start time.Time, end time.Time) time.Duration {
+	overlapStart := interval.Start
+	if start.After(overlapStart) {
+		overlapStart = start
+	}
+	overlapEnd := interval.End
+	if end.Before(overlapEnd) {
+		overlapEnd = end
+	}
+	return overlapEnd.Sub(overlapStart)
+}
+
+// overlapFraction returns the fraction of the interval that falls between start and end
+func overlapFraction(interval Interval, start time.Time, end time.Time) float64 {
+	overlap := overlapDuration(interval, start, end)
+	length := interval.End.Sub(interval.Start)
+	if overlap <= 0 || length <= 0 {
+		return 0
+	}
+	return overlap.Seconds() / length.Seconds()
+}
+
+// ParseValidTime parses an ISO-8601 "start/duration" interval as used by NWS gridpoint data
+func ParseValidTime(validTime string) (time.Time, time.Time, error) {
+	startString, durationString, found := strings.Cut(validTime, "/")
+	if !found {
+		return time.Time{}, time.Time{}, fmt.Errorf("valid time %q is not an interval", validTime)
+	}
+
+	start, err := time.Parse(time.RFC3339, startString)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("valid time %q has an invalid start: %w", validTime, err)
+	}
+
+	duration, err := ParseISO8601Duration(durationString)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("valid time %q has an invalid duration: %w", validTime, err)
+	}
+
+	return start, start.Add(duration), nil
+}
+
+var iso8601DurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ParseISO8601Duration parses the day, hour, minute and second components of an ISO-8601 duration, e.g. "P1DT6H"
+func ParseISO8601Duration(s string) (time.Duration, error) {
+	matches := iso8601DurationRegex.FindStringSubmatch(s)
+	if matches == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("unsupported ISO-8601 duration %q", s)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	var duration time.Duration
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return 0, err
+		}
+		duration += time.Duration(n) * unit
+	}
+
+	return duration, nil
+}
+
+// AddQuantitativeForecast fills in the precipitation amounts, snowfall, sky cover, gusts and dewpoint
+// of each period from the raw gridpoint data
+func AddQuantitativeForecast(periods []SimplifiedForecastPeriods, data GridpointDataResponse) {
+	properties := data.Properties
+	for i := range periods {
+		start, end := periods[i].StartTime, periods[i].EndTime
+
+		if qpf, ok := properties.QuantitativePrecipitation.Sum(start, end); ok {
+			periods[i].PrecipitationAmount = convertAmount(qpf, properties.QuantitativePrecipitation.UOM)
+		}
+
+		if snowfall, ok := properties.SnowfallAmount.Sum(start, end); ok {
+			periods[i].SnowfallAmount = convertAmount(snowfall, properties.SnowfallAmount.UOM)
+		}
+
+		if skyCover, ok := properties.SkyCover.Mean(start, end); ok {
+			periods[i].SkyCover = round(&skyCover)
+		}
+
+		if gust, ok := properties.WindGust.Max(start, end); ok {
+			periods[i].WindGust = convertSpeed(QuantitativeValue{UnitCode: properties.WindGust.UOM, Value: &gust}, UnitsUS)
+		}
+
+		if dewpoint, ok := properties.Dewpoint.Mean(start, end); ok {
+			periods[i].Dewpoint = convertTemperature(QuantitativeValue{UnitCode: properties.Dewpoint.UOM, Value: &dewpoint}, UnitsUS)
+		}
+	}
+}
+
+// convertAmount converts a precipitation amount to inches, rounded to the hundredth
+func convertAmount(amount float64, uom string) *float64 {
+	inches := amount
+	switch uom {
+	case "wmoUnit:mm":
+		inches = amount / 25.4
+	case "wmoUnit:m":
+		inches = amount / 0.0254
+	}
+
+	inches = math.Round(inches*100) / 100
+	return &inches
+}
@@ -0,0 +1,42 @@
+package nws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseISO8601Duration(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "hours", s: "PT1H", want: time.Hour},
+		{name: "days", s: "P2D", want: 48 * time.Hour},
+		{name: "days and hours", s: "P1DT6H", want: 30 * time.Hour},
+		{name: "all units", s: "P1DT2H3M4S", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{name: "minutes", s: "PT30M", want: 30 * time.Minute},
+		{name: "seconds", s: "PT45S", want: 45 * time.Second},
+		{name: "zero", s: "PT0H", want: 0},
+		{name: "empty", s: "", wantErr: true},
+		{name: "no units", s: "P", wantErr: true},
+		{name: "empty time part", s: "P1DT", wantErr: true},
+		{name: "weeks", s: "P1W", wantErr: true},
+		{name: "years", s: "P1Y", wantErr: true},
+		{name: "fractional", s: "PT1.5H", wantErr: true},
+		{name: "missing designator", s: "1H", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseISO8601Duration(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseISO8601Duration(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseISO8601Duration(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
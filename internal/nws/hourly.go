@@ -0,0 +1,135 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"time"
+)
+
+type HourlyForecastResponse struct {
+	Properties struct {
+		Units             string    `json:"units"`
+		ForecastGenerator string    `json:"forecastGenerator"`
+		GeneratedAt       time.Time `json:"generatedAt"`
+		UpdateTime        time.Time `json:"updateTime"`
+		ValidTimes        string    `json:"validTimes"`
+		Periods           []struct {
+			Number                     int       `json:"number"`
+			StartTime                  time.Time `json:"startTime"`
+			EndTime                    time.Time `json:"endTime"`
+			IsDaytime                  bool      `json:"isDaytime"`
+			Temperature                int       `json:"temperature"`
+			TemperatureUnit            string    `json:"temperatureUnit"`
+			ProbabilityOfPrecipitation struct {
+				UnitCode string `json:"unitCode"`
+				Value    int    `json:"value"`
+			} `json:"probabilityOfPrecipitation"`
+			Dewpoint struct {
+				UnitCode string  `json:"unitCode"`
+				Value    float64 `json:"value"`
+			} `json:"dewpoint"`
+			RelativeHumidity struct {
+				UnitCode string `json:"unitCode"`
+				Value    int    `json:"value"`
+			} `json:"relativeHumidity"`
+			WindSpeed     string `json:"windSpeed"`
+			WindDirection string `json:"windDirection"`
+			Icon          string `json:"icon"`
+			ShortForecast string `json:"shortForecast"`
+		} `json:"periods"`
+	} `json:"properties"`
+}
+
+type SimplifiedHourlyForecastPeriod struct {
+	Number                     int       `json:"number"`
+	StartTime                  time.Time `json:"start_time"`
+	EndTime                    time.Time `json:"end_time"`
+	IsDaytime                  bool      `json:"is_daytime"`
+	Temperature                int       `json:"temperature"`
+	ProbabilityOfPrecipitation int       `json:"probability_of_precipitation"`
+	RelativeHumidity           int       `json:"relative_humidity"`
+	Dewpoint                   int       `json:"dewpoint"`
+	WindSpeed                  string    `json:"wind_speed"`
+	WindDirection              string    `json:"wind_direction"`
+	ShortForecast              string    `json:"short_forecast"`
+
+	// Icon is the NWS icon URL, which is left out of LLM input so it does not sway the LLM's icon choice
+	Icon string `json:"-"`
+}
+
+func (nc *NWSClient) GetHourlyForecast(ctx context.Context, gridpoints string) (HourlyForecastResponse, error) {
+	forecastURL := "https://api.weather.gov/gridpoints/" + gridpoints + "/forecast/hourly"
+	slog.Info("getting hourly forecast", slog.String("url", forecastURL))
+	resp, err := nc.get(ctx, forecastURL)
+	if err != nil {
+		slog.Error("could not get hourly forecast", slog.String("error", err.Error()))
+		return HourlyForecastResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var forecast HourlyForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&forecast); err != nil {
+		slog.Error("could not decode hourly forecast", slog.String("error", err.Error()))
+		return HourlyForecastResponse{}, err
+	}
+
+	return forecast, nil
+}
+
+// GetSimplifiedHourlyForecastNPeriods returns at most n upcoming hourly periods, or all of them when n is negative
+func (nc *NWSClient) GetSimplifiedHourlyForecastNPeriods(ctx context.Context, gridpoints string, n int) ([]SimplifiedHourlyForecastPeriod, error) {
+	forecast, err := nc.GetHourlyForecast(ctx, gridpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := hourlyForecastResponseToSimplifiedHourlyForecastPeriods(forecast, time.Now())
+	if n < 0 || n > len(periods) {
+		return periods, nil
+	}
+
+	return periods[:n], nil
+}
+
+// hourlyForecastResponseToSimplifiedHourlyForecastPeriods simplifies the periods that have not ended by now,
+// as a forecast that is a few hours old still starts with hours that have passed
+func hourlyForecastResponseToSimplifiedHourlyForecastPeriods(forecast HourlyForecastResponse, now time.Time) []SimplifiedHourlyForecastPeriod {
+	var periods []SimplifiedHourlyForecastPeriod
+	for _, period := range forecast.Properties.Periods {
+		if !period.EndTime.After(now) {
+			continue
+		}
+
+		periods = append(periods, SimplifiedHourlyForecastPeriod{
+			Number:                     period.Number,
+			StartTime:                  period.StartTime,
+			EndTime:                    period.EndTime,
+			IsDaytime:                  period.IsDaytime,
+			Temperature:                period.Temperature,
+			ProbabilityOfPrecipitation: period.ProbabilityOfPrecipitation.Value,
+			RelativeHumidity:           period.RelativeHumidity.Value,
+			Dewpoint:                   toFahrenheit(period.Dewpoint.UnitCode, period.Dewpoint.Value),
+			WindSpeed:                  period.WindSpeed,
+			WindDirection:              period.WindDirection,
+			ShortForecast:              period.ShortForecast,
+			Icon:                       period.Icon,
+		})
+	}
+	return periods
+}
+
+// toFahrenheit converts a temperature to the nearest whole degree fahrenheit, converting from celsius only
+// when its unit code says it is in celsius
+func toFahrenheit(unitCode string, value float64) int {
+	if unitCode == "wmoUnit:degC" {
+		return celsiusToFahrenheit(value)
+	}
+	return int(math.Round(value))
+}
+
+// celsiusToFahrenheit converts a temperature in degrees celsius to the nearest whole degree fahrenheit
+func celsiusToFahrenheit(c float64) int {
+	return int(math.Round(c*9/5 + 32))
+}
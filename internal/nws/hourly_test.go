@@ -0,0 +1,52 @@
+package nws
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHourlyForecastResponseToSimplifiedHourlyForecastPeriods(t *testing.T) {
+	var forecast HourlyForecastResponse
+	err := json.Unmarshal([]byte(`{"properties": {"periods": [
+		{"number": 1, "startTime": "2026-10-16T08:00:00-07:00", "endTime": "2026-10-16T09:00:00-07:00", "dewpoint": {"unitCode": "wmoUnit:degC", "value": 10}},
+		{"number": 2, "startTime": "2026-10-16T09:00:00-07:00", "endTime": "2026-10-16T10:00:00-07:00", "dewpoint": {"unitCode": "wmoUnit:degC", "value": 10}},
+		{"number": 3, "startTime": "2026-10-16T10:00:00-07:00", "endTime": "2026-10-16T11:00:00-07:00", "dewpoint": {"unitCode": "wmoUnit:degC", "value": -2.5}},
+		{"number": 4, "startTime": "2026-10-16T11:00:00-07:00", "endTime": "2026-10-16T12:00:00-07:00", "dewpoint": {"unitCode": "wmoUnit:degF", "value": 49.6}}
+	]}}`), &forecast)
+	if err != nil {
+		t.Fatalf("could not decode forecast: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		now           string
+		wantNumbers   []int
+		wantDewpoints []int
+	}{
+		{name: "before the forecast", now: "2026-10-16T07:00:00-07:00", wantNumbers: []int{1, 2, 3, 4}, wantDewpoints: []int{50, 50, 28, 50}},
+		{name: "during the second hour", now: "2026-10-16T09:30:00-07:00", wantNumbers: []int{2, 3, 4}, wantDewpoints: []int{50, 28, 50}},
+		{name: "at the end of an hour", now: "2026-10-16T10:00:00-07:00", wantNumbers: []int{3, 4}, wantDewpoints: []int{28, 50}},
+		{name: "after the forecast", now: "2026-10-16T12:00:00-07:00", wantNumbers: nil, wantDewpoints: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+
+			var numbers, dewpoints []int
+			for _, period := range hourlyForecastResponseToSimplifiedHourlyForecastPeriods(forecast, now) {
+				numbers = append(numbers, period.Number)
+				dewpoints = append(dewpoints, period.Dewpoint)
+			}
+
+			if !slices.Equal(numbers, tt.wantNumbers) {
+				t.Errorf("periods = %v, want %v", numbers, tt.wantNumbers)
+			}
+			if !slices.Equal(dewpoints, tt.wantDewpoints) {
+				t.Errorf("dewpoints = %v, want %v", dewpoints, tt.wantDewpoints)
+			}
+		})
+	}
+}
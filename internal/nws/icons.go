@@ -0,0 +1,55 @@
+package nws
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// IconCondition is a single weather condition of an NWS forecast icon, such as "rain" with a 40%
+// probability of precipitation. Probability is 0 when none is given
+type IconCondition struct {
+	Code        string
+	Probability int
+}
+
+// ForecastIcon is a parsed NWS forecast icon URL. A period whose weather changes has two conditions,
+// the first of which comes first
+type ForecastIcon struct {
+	IsDaytime  bool
+	Conditions []IconCondition
+}
+
+// ParseIconURL parses an NWS forecast icon URL such as
+// https://api.weather.gov/icons/land/night/rain,40/tsra,60?size=medium. ok is false when the URL is not
+// an NWS forecast icon
+func ParseIconURL(iconURL string) (ForecastIcon, bool) {
+	u, err := url.Parse(iconURL)
+	if err != nil {
+		return ForecastIcon{}, false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	// the path is icons/{land,marine}/{day,night}/{condition}[/{condition}]
+	i := slices.Index(segments, "icons")
+	if i < 0 || len(segments) < i+4 {
+		return ForecastIcon{}, false
+	}
+
+	timeOfDay := segments[i+2]
+	if timeOfDay != "day" && timeOfDay != "night" {
+		return ForecastIcon{}, false
+	}
+
+	icon := ForecastIcon{IsDaytime: timeOfDay == "day"}
+	for _, segment := range segments[i+3:] {
+		code, probability, _ := strings.Cut(segment, ",")
+		condition := IconCondition{Code: code}
+		condition.Probability, _ = strconv.Atoi(probability)
+		icon.Conditions = append(icon.Conditions, condition)
+	}
+
+	return icon, true
+}
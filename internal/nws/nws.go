@@ -1,6 +1,7 @@
 package nws
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -8,7 +9,10 @@ import (
 )
 
 type NWSClient struct {
-	httpClient *http.Client
+	httpClient   *http.Client
+	userAgent    string
+	maxRetries   int
+	retryBackoff time.Duration
 }
 
 type ForecastResponse struct {
@@ -51,26 +55,43 @@ type ForecastResponse struct {
 }
 
 type SimplifiedForecastPeriods struct {
-	DetailedForecast string    `json:"detailed_forecast"`
-	ShortForecast    string    `json:"short_forecast"`
-	StartTime        time.Time `json:"start_time"`
-	EndTime          time.Time `json:"end_time"`
-	Temperature      int       `json:"temperature"`
-	WindSpeed        string    `json:"wind_speed"`
-	WindDirection    string    `json:"wind_direction"`
-	Name             string    `json:"name"`
+	DetailedForecast           string    `json:"detailed_forecast"`
+	ShortForecast              string    `json:"short_forecast"`
+	StartTime                  time.Time `json:"start_time"`
+	EndTime                    time.Time `json:"end_time"`
+	Temperature                int       `json:"temperature"`
+	ProbabilityOfPrecipitation int       `json:"probability_of_precipitation"`
+	WindSpeed                  string    `json:"wind_speed"`
+	WindDirection              string    `json:"wind_direction"`
+	Name                       string    `json:"name"`
+	IsDaytime                  bool      `json:"is_daytime"`
+
+	// Icon is the NWS icon URL, which is left out of LLM input so it does not sway the LLM's icon choice
+	Icon string `json:"-"`
+
+	// quantitative forecast from the raw gridpoint data, see AddQuantitativeForecast
+	PrecipitationAmount *float64 `json:"precipitation_amount,omitempty"`
+	SnowfallAmount      *float64 `json:"snowfall_amount,omitempty"`
+	SkyCover            *float64 `json:"sky_cover,omitempty"`
+	WindGust            *float64 `json:"wind_gust,omitempty"`
+	Dewpoint            *float64 `json:"dewpoint,omitempty"`
 }
 
-func NewNWSClient(httpClient *http.Client) *NWSClient {
+// NewNWSClient creates a new NWS API client. NWS requires a User-Agent identifying the application and a
+// contact, and failed requests are retried up to maxRetries times starting from retryBackoff
+func NewNWSClient(httpClient *http.Client, userAgent string, maxRetries int, retryBackoff time.Duration) *NWSClient {
 	return &NWSClient{
-		httpClient: httpClient,
+		httpClient:   httpClient,
+		userAgent:    userAgent,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
 	}
 }
 
-func (nc *NWSClient) GetForecast(gridpoints string) (ForecastResponse, error) {
+func (nc *NWSClient) GetForecast(ctx context.Context, gridpoints string) (ForecastResponse, error) {
 	forecastURL := "https://api.weather.gov/gridpoints/" + gridpoints + "/forecast"
 	slog.Info("getting forecast", slog.String("url", forecastURL))
-	resp, err := nc.httpClient.Get(forecastURL)
+	resp, err := nc.get(ctx, forecastURL)
 	if err != nil {
 		slog.Error("could not get forecast", slog.String("error", err.Error()))
 		return ForecastResponse{}, err
@@ -86,8 +107,61 @@ func (nc *NWSClient) GetForecast(gridpoints string) (ForecastResponse, error) {
 	return forecast, nil
 }
 
-func (nc *NWSClient) GetSimplifiedForecast(gridpoints string) ([]SimplifiedForecastPeriods, error) {
-	forecast, err := nc.GetForecast(gridpoints)
+// ForecastVersion identifies a revision of a forecast, both by the HTTP validators NWS returned with it
+// and by the times in the forecast itself
+type ForecastVersion struct {
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"last_modified"`
+	UpdateTime   time.Time `json:"update_time"`
+	GeneratedAt  time.Time `json:"generated_at"`
+}
+
+// SameForecast reports whether two versions describe the same forecast. GeneratedAt changes whenever NWS
+// re-renders the document, so only UpdateTime, the time the forecaster last edited the grid, is compared
+func (v ForecastVersion) SameForecast(other ForecastVersion) bool {
+	return !v.UpdateTime.IsZero() && v.UpdateTime.Equal(other.UpdateTime)
+}
+
+// GetForecastIfModified fetches a forecast with a conditional request against a previously seen version.
+// When NWS responds 304 Not Modified, modified is false and the returned version is the one given
+func (nc *NWSClient) GetForecastIfModified(ctx context.Context, gridpoints string, version ForecastVersion) (ForecastResponse, ForecastVersion, bool, error) {
+	headers := http.Header{}
+	if version.ETag != "" {
+		headers.Set("If-None-Match", version.ETag)
+	}
+	if version.LastModified != "" {
+		headers.Set("If-Modified-Since", version.LastModified)
+	}
+
+	forecastURL := "https://api.weather.gov/gridpoints/" + gridpoints + "/forecast"
+	slog.Info("getting forecast if modified", slog.String("url", forecastURL))
+	resp, err := nc.getWithHeaders(ctx, forecastURL, headers)
+	if err != nil {
+		slog.Error("could not get forecast", slog.String("error", err.Error()))
+		return ForecastResponse{}, ForecastVersion{}, false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return ForecastResponse{}, version, false, nil
+	}
+
+	var forecast ForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&forecast); err != nil {
+		slog.Error("could not decode forecast", slog.String("error", err.Error()))
+		return ForecastResponse{}, ForecastVersion{}, false, err
+	}
+
+	return forecast, ForecastVersion{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		UpdateTime:   forecast.Properties.UpdateTime,
+		GeneratedAt:  forecast.Properties.GeneratedAt,
+	}, true, nil
+}
+
+func (nc *NWSClient) GetSimplifiedForecast(ctx context.Context, gridpoints string) ([]SimplifiedForecastPeriods, error) {
+	forecast, err := nc.GetForecast(ctx, gridpoints)
 	if err != nil {
 		return nil, err
 	}
@@ -95,31 +169,40 @@ func (nc *NWSClient) GetSimplifiedForecast(gridpoints string) ([]SimplifiedForec
 	return forecastResponeToSimplifiedForecastPeriods(forecast), nil
 }
 
-func (nc *NWSClient) GetSimplifiedForecastNPeriods(gridpoints string, n int) ([]SimplifiedForecastPeriods, error) {
-	forecast, err := nc.GetForecast(gridpoints)
+func (nc *NWSClient) GetSimplifiedForecastNPeriods(ctx context.Context, gridpoints string, n int) ([]SimplifiedForecastPeriods, error) {
+	forecast, err := nc.GetForecast(ctx, gridpoints)
 	if err != nil {
 		return nil, err
 	}
 
-	if n == -1 {
-		return forecastResponeToSimplifiedForecastPeriods(forecast), nil
+	return SimplifyForecastNPeriods(forecast, n), nil
+}
+
+// SimplifyForecastNPeriods returns at most n simplified periods from a forecast, or all of them when n is negative
+func SimplifyForecastNPeriods(forecast ForecastResponse, n int) []SimplifiedForecastPeriods {
+	periods := forecastResponeToSimplifiedForecastPeriods(forecast)
+	if n < 0 || n > len(periods) {
+		return periods
 	}
 
-	return forecastResponeToSimplifiedForecastPeriods(forecast)[:n], nil
+	return periods[:n]
 }
 
 func forecastResponeToSimplifiedForecastPeriods(forecast ForecastResponse) []SimplifiedForecastPeriods {
 	var periods []SimplifiedForecastPeriods
 	for _, period := range forecast.Properties.Periods {
 		periods = append(periods, SimplifiedForecastPeriods{
-			DetailedForecast: period.DetailedForecast,
-			ShortForecast:    period.ShortForecast,
-			StartTime:        period.StartTime,
-			EndTime:          period.EndTime,
-			Temperature:      period.Temperature,
-			WindSpeed:        period.WindSpeed,
-			WindDirection:    period.WindDirection,
-			Name:             period.Name,
+			DetailedForecast:           period.DetailedForecast,
+			ShortForecast:              period.ShortForecast,
+			StartTime:                  period.StartTime,
+			EndTime:                    period.EndTime,
+			Temperature:                period.Temperature,
+			ProbabilityOfPrecipitation: period.ProbabilityOfPrecipitation.Value,
+			WindSpeed:                  period.WindSpeed,
+			WindDirection:              period.WindDirection,
+			Name:                       period.Name,
+			IsDaytime:                  period.IsDaytime,
+			Icon:                       period.Icon,
 		})
 	}
 	return periods
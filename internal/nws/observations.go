@@ -0,0 +1,278 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+)
+
+// Units selects the unit system observations are converted to
+type Units string
+
+const (
+	UnitsUS Units = "us"
+	UnitsSI Units = "si"
+)
+
+// ParseUnits parses a unit system name, defaulting to UnitsUS when empty
+func ParseUnits(s string) (Units, error) {
+	switch Units(strings.ToLower(s)) {
+	case "", UnitsUS:
+		return UnitsUS, nil
+	case UnitsSI:
+		return UnitsSI, nil
+	default:
+		return "", fmt.Errorf("unknown units %q, expected %q or %q", s, UnitsUS, UnitsSI)
+	}
+}
+
+// QuantitativeValue is an NWS measurement with a WMO unit code. Value is nil when the station did not report it
+type QuantitativeValue struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+type StationsResponse struct {
+	Features []struct {
+		Properties struct {
+			StationIdentifier string `json:"stationIdentifier"`
+			Name              string `json:"name"`
+			TimeZone          string `json:"timeZone"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+type ObservationResponse struct {
+	Properties struct {
+		Station            string            `json:"station"`
+		Timestamp          time.Time         `json:"timestamp"`
+		TextDescription    string            `json:"textDescription"`
+		Temperature        QuantitativeValue `json:"temperature"`
+		Dewpoint           QuantitativeValue `json:"dewpoint"`
+		WindDirection      QuantitativeValue `json:"windDirection"`
+		WindSpeed          QuantitativeValue `json:"windSpeed"`
+		WindGust           QuantitativeValue `json:"windGust"`
+		BarometricPressure QuantitativeValue `json:"barometricPressure"`
+		SeaLevelPressure   QuantitativeValue `json:"seaLevelPressure"`
+		Visibility         QuantitativeValue `json:"visibility"`
+		RelativeHumidity   QuantitativeValue `json:"relativeHumidity"`
+		WindChill          QuantitativeValue `json:"windChill"`
+		HeatIndex          QuantitativeValue `json:"heatIndex"`
+	} `json:"properties"`
+}
+
+// Station is an observation station near a grid point
+type Station struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SimplifiedObservation is an observation converted to a single unit system. Measurements the
+// station did not report are nil
+type SimplifiedObservation struct {
+	StationID            string    `json:"station_id"`
+	StationName          string    `json:"station_name"`
+	ObservedAt           time.Time `json:"observed_at"`
+	Description          string    `json:"description"`
+	Units                Units     `json:"units"`
+	Temperature          *float64  `json:"temperature"`
+	Dewpoint             *float64  `json:"dewpoint"`
+	RelativeHumidity     *float64  `json:"relative_humidity"`
+	WindSpeed            *float64  `json:"wind_speed"`
+	WindGust             *float64  `json:"wind_gust"`
+	WindDirection        string    `json:"wind_direction"`
+	WindDirectionDegrees *float64  `json:"wind_direction_degrees"`
+	Pressure             *float64  `json:"pressure"`
+	Visibility           *float64  `json:"visibility"`
+}
+
+func (nc *NWSClient) GetStations(ctx context.Context, gridpoints string) (StationsResponse, error) {
+	stationsURL := "https://api.weather.gov/gridpoints/" + gridpoints + "/stations"
+	slog.Info("getting stations", slog.String("url", stationsURL))
+	resp, err := nc.get(ctx, stationsURL)
+	if err != nil {
+		slog.Error("could not get stations", slog.String("error", err.Error()))
+		return StationsResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var stations StationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stations); err != nil {
+		slog.Error("could not decode stations", slog.String("error", err.Error()))
+		return StationsResponse{}, err
+	}
+
+	return stations, nil
+}
+
+// GetNearestStation returns the observation station closest to a grid point. The NWS API lists
+// stations for a grid point in order of distance
+func (nc *NWSClient) GetNearestStation(ctx context.Context, gridpoints string) (Station, error) {
+	stations, err := nc.GetStations(ctx, gridpoints)
+	if err != nil {
+		return Station{}, err
+	}
+
+	if len(stations.Features) == 0 {
+		return Station{}, fmt.Errorf("no observation stations found for %s", gridpoints)
+	}
+
+	return Station{
+		ID:   stations.Features[0].Properties.StationIdentifier,
+		Name: stations.Features[0].Properties.Name,
+	}, nil
+}
+
+func (nc *NWSClient) GetLatestObservation(ctx context.Context, stationID string) (ObservationResponse, error) {
+	observationURL := "https://api.weather.gov/stations/" + stationID + "/observations/latest"
+	slog.Info("getting latest observation", slog.String("url", observationURL))
+	resp, err := nc.get(ctx, observationURL)
+	if err != nil {
+		slog.Error("could not get latest observation", slog.String("error", err.Error()))
+		return ObservationResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var observation ObservationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&observation); err != nil {
+		slog.Error("could not decode latest observation", slog.String("error", err.Error()))
+		return ObservationResponse{}, err
+	}
+
+	return observation, nil
+}
+
+func (nc *NWSClient) GetSimplifiedLatestObservation(ctx context.Context, station Station, units Units) (SimplifiedObservation, error) {
+	observation, err := nc.GetLatestObservation(ctx, station.ID)
+	if err != nil {
+		return SimplifiedObservation{}, err
+	}
+
+	return SimplifyObservation(observation, station, units), nil
+}
+
+// SimplifyObservation converts an observation to the given unit system
+func SimplifyObservation(observation ObservationResponse, station Station, units Units) SimplifiedObservation {
+	properties := observation.Properties
+
+	// sea level pressure is more comparable between stations, but not every station reports it
+	pressure := properties.SeaLevelPressure
+	if pressure.Value == nil {
+		pressure = properties.BarometricPressure
+	}
+
+	windDirection := ""
+	if properties.WindDirection.Value != nil {
+		windDirection = degreesToCardinal(*properties.WindDirection.Value)
+	}
+
+	return SimplifiedObservation{
+		StationID:            station.ID,
+		StationName:          station.Name,
+		ObservedAt:           properties.Timestamp,
+		Description:          properties.TextDescription,
+		Units:                units,
+		Temperature:          convertTemperature(properties.Temperature, units),
+		Dewpoint:             convertTemperature(properties.Dewpoint, units),
+		RelativeHumidity:     round(properties.RelativeHumidity.Value),
+		WindSpeed:            convertSpeed(properties.WindSpeed, units),
+		WindGust:             convertSpeed(properties.WindGust, units),
+		WindDirection:        windDirection,
+		WindDirectionDegrees: round(properties.WindDirection.Value),
+		Pressure:             convertPressure(pressure, units),
+		Visibility:           convertDistance(properties.Visibility, units),
+	}
+}
+
+// convertTemperature converts a temperature to fahrenheit for UnitsUS or celsius for UnitsSI
+func convertTemperature(qv QuantitativeValue, units Units) *float64 {
+	if qv.Value == nil {
+		return nil
+	}
+
+	celsius := *qv.Value
+	if qv.UnitCode == "wmoUnit:degF" {
+		celsius = (celsius - 32) * 5 / 9
+	}
+
+	if units == UnitsSI {
+		return round(&celsius)
+	}
+
+	fahrenheit := celsius*9/5 + 32
+	return round(&fahrenheit)
+}
+
+// convertSpeed converts a speed to miles per hour for UnitsUS or kilometers per hour for UnitsSI
+func convertSpeed(qv QuantitativeValue, units Units) *float64 {
+	if qv.Value == nil {
+		return nil
+	}
+
+	kilometersPerHour := *qv.Value
+	if qv.UnitCode == "wmoUnit:m_s-1" {
+		kilometersPerHour *= 3.6
+	}
+
+	if units == UnitsSI {
+		return round(&kilometersPerHour)
+	}
+
+	milesPerHour := kilometersPerHour / 1.609344
+	return round(&milesPerHour)
+}
+
+// convertPressure converts a pressure to inches of mercury for UnitsUS or hectopascals for UnitsSI
+func convertPressure(qv QuantitativeValue, units Units) *float64 {
+	if qv.Value == nil {
+		return nil
+	}
+
+	hectopascals := *qv.Value / 100
+	if units == UnitsSI {
+		return round(&hectopascals)
+	}
+
+	inchesOfMercury := math.Round(hectopascals/33.8639*100) / 100
+	return &inchesOfMercury
+}
+
+// convertDistance converts a distance to miles for UnitsUS or kilometers for UnitsSI
+func convertDistance(qv QuantitativeValue, units Units) *float64 {
+	if qv.Value == nil {
+		return nil
+	}
+
+	kilometers := *qv.Value / 1000
+	if units == UnitsSI {
+		return round(&kilometers)
+	}
+
+	miles := kilometers / 1.609344
+	return round(&miles)
+}
+
+// round rounds a value to one decimal place
+func round(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+
+	rounded := math.Round(*v*10) / 10
+	return &rounded
+}
+
+var cardinalDirections = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// degreesToCardinal converts a compass bearing to one of the sixteen cardinal directions used by NWS forecasts
+func degreesToCardinal(degrees float64) string {
+	index := int(math.Round(math.Mod(degrees, 360)/22.5)) % len(cardinalDirections)
+	if index < 0 {
+		index += len(cardinalDirections)
+	}
+	return cardinalDirections[index]
+}
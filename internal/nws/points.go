@@ -0,0 +1,111 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+var nonAlphanumericRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+type PointResponse struct {
+	Properties struct {
+		ID                  string `json:"@id"`
+		GridID              string `json:"gridId"`
+		GridX               int    `json:"gridX"`
+		GridY               int    `json:"gridY"`
+		Forecast            string `json:"forecast"`
+		ForecastHourly      string `json:"forecastHourly"`
+		ForecastGridData    string `json:"forecastGridData"`
+		ObservationStations string `json:"observationStations"`
+		TimeZone            string `json:"timeZone"`
+		RelativeLocation    struct {
+			Properties struct {
+				City  string `json:"city"`
+				State string `json:"state"`
+			} `json:"properties"`
+		} `json:"relativeLocation"`
+	} `json:"properties"`
+}
+
+// Point is the simplified result of resolving a latitude and longitude with the NWS /points endpoint
+type Point struct {
+	Latitude               float64   `json:"latitude"`
+	Longitude              float64   `json:"longitude"`
+	GridPoint              GridPoint `json:"grid_point"`
+	ForecastURL            string    `json:"forecast_url"`
+	ForecastHourlyURL      string    `json:"forecast_hourly_url"`
+	ForecastGridDataURL    string    `json:"forecast_grid_data_url"`
+	ObservationStationsURL string    `json:"observation_stations_url"`
+	TimeZone               string    `json:"time_zone"`
+	City                   string    `json:"city"`
+	State                  string    `json:"state"`
+}
+
+// PlaceName returns a url-safe name for the point's relative location, e.g. lake-forest-park-wa
+func (p Point) PlaceName() string {
+	return NormalizePlaceName(p.City + " " + p.State)
+}
+
+// NormalizePlaceName lowercases a place name and replaces anything that isn't a letter or digit with a dash
+func NormalizePlaceName(name string) string {
+	return strings.Trim(nonAlphanumericRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func (nc *NWSClient) GetPoint(ctx context.Context, lat float64, lon float64) (PointResponse, error) {
+	if err := ValidateCoordinates(lat, lon); err != nil {
+		return PointResponse{}, err
+	}
+
+	// the NWS API only accepts up to four decimal places of precision
+	pointURL := fmt.Sprintf("https://api.weather.gov/points/%.4f,%.4f", lat, lon)
+	slog.Info("getting point", slog.String("url", pointURL))
+	resp, err := nc.get(ctx, pointURL)
+	if err != nil {
+		slog.Error("could not get point", slog.String("error", err.Error()))
+		return PointResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var point PointResponse
+	if err := json.NewDecoder(resp.Body).Decode(&point); err != nil {
+		slog.Error("could not decode point", slog.String("error", err.Error()))
+		return PointResponse{}, err
+	}
+
+	return point, nil
+}
+
+// ResolvePoint resolves a latitude and longitude to the grid point, forecast URLs and relative location that cover it
+func (nc *NWSClient) ResolvePoint(ctx context.Context, lat float64, lon float64) (Point, error) {
+	point, err := nc.GetPoint(ctx, lat, lon)
+	if err != nil {
+		return Point{}, err
+	}
+
+	gp := GridPoint{
+		Office: point.Properties.GridID,
+		X:      point.Properties.GridX,
+		Y:      point.Properties.GridY,
+	}
+
+	if err := gp.Validate(); err != nil {
+		return Point{}, fmt.Errorf("could not resolve %f,%f: %w", lat, lon, err)
+	}
+
+	return Point{
+		Latitude:               lat,
+		Longitude:              lon,
+		GridPoint:              gp,
+		ForecastURL:            point.Properties.Forecast,
+		ForecastHourlyURL:      point.Properties.ForecastHourly,
+		ForecastGridDataURL:    point.Properties.ForecastGridData,
+		ObservationStationsURL: point.Properties.ObservationStations,
+		TimeZone:               point.Properties.TimeZone,
+		City:                   point.Properties.RelativeLocation.Properties.City,
+		State:                  point.Properties.RelativeLocation.Properties.State,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type ProductsResponse struct {
+	Graph []struct {
+		ID              string    `json:"id"`
+		WMOCollectiveID string    `json:"wmoCollectiveId"`
+		IssuingOffice   string    `json:"issuingOffice"`
+		IssuanceTime    time.Time `json:"issuanceTime"`
+		ProductCode     string    `json:"productCode"`
+		ProductName     string    `json:"productName"`
+	} `json:"@graph"`
+}
+
+type ProductResponse struct {
+	ID              string    `json:"id"`
+	WMOCollectiveID string    `json:"wmoCollectiveId"`
+	IssuingOffice   string    `json:"issuingOffice"`
+	IssuanceTime    time.Time `json:"issuanceTime"`
+	ProductCode     string    `json:"productCode"`
+	ProductName     string    `json:"productName"`
+	ProductText     string    `json:"productText"`
+}
+
+// GetProducts lists the text products of a type issued for a location, newest first. For example,
+// Area Forecast Discussions for a forecast office are GetProducts("AFD", "SEW")
+func (nc *NWSClient) GetProducts(ctx context.Context, productType string, location string) (ProductsResponse, error) {
+	productsURL := fmt.Sprintf("https://api.weather.gov/products/types/%s/locations/%s", productType, location)
+	slog.Info("getting products", slog.String("url", productsURL))
+	resp, err := nc.get(ctx, productsURL)
+	if err != nil {
+		slog.Error("could not get products", slog.String("error", err.Error()))
+		return ProductsResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var products ProductsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		slog.Error("could not decode products", slog.String("error", err.Error()))
+		return ProductsResponse{}, err
+	}
+
+	return products, nil
+}
+
+func (nc *NWSClient) GetProduct(ctx context.Context, id string) (ProductResponse, error) {
+	productURL := "https://api.weather.gov/products/" + id
+	slog.Info("getting product", slog.String("url", productURL))
+	resp, err := nc.get(ctx, productURL)
+	if err != nil {
+		slog.Error("could not get product", slog.String("error", err.Error()))
+		return ProductResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var product ProductResponse
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		slog.Error("could not decode product", slog.String("error", err.Error()))
+		return ProductResponse{}, err
+	}
+
+	return product, nil
+}
+
+// GetLatestProductID returns the ID of the most recently issued product of a type for a location
+func (nc *NWSClient) GetLatestProductID(ctx context.Context, productType string, location string) (string, error) {
+	products, err := nc.GetProducts(ctx, productType, location)
+	if err != nil {
+		return "", err
+	}
+
+	if len(products.Graph) == 0 {
+		return "", fmt.Errorf("no %s products found for %s", productType, location)
+	}
+
+	latest := products.Graph[0]
+	for _, product := range products.Graph[1:] {
+		if product.IssuanceTime.After(latest.IssuanceTime) {
+			latest = product
+		}
+	}
+
+	return latest.ID, nil
+}
@@ -0,0 +1,100 @@
+package nws
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	windSpeedRangeRegex = regexp.MustCompile(`(\d+)(?:\s*to\s*(\d+))?\s*(?:mph)?`)
+	windGustRegex       = regexp.MustCompile(`(?i)\bG\s*(\d+)|gusts?\D*?(\d+)`)
+)
+
+// Wind is a parsed NWS wind speed string. Speeds are in miles per hour and Gust is 0 when no gust is given
+type Wind struct {
+	MinSpeed int
+	MaxSpeed int
+	Gust     int
+}
+
+// ParseWind parses an NWS wind speed string such as "5 mph", "1 to 6 mph SW" or "10 to 15 mph G25". ok is
+// false when the string contains no sustained speed, though a gust may still have been parsed
+func ParseWind(windSpeed string) (Wind, bool) {
+	var wind Wind
+	if strings.EqualFold(strings.TrimSpace(windSpeed), "calm") {
+		return wind, true
+	}
+
+	sustained := windSpeed
+	if loc := windGustRegex.FindStringSubmatchIndex(windSpeed); loc != nil {
+		for _, group := range [][2]int{{loc[2], loc[3]}, {loc[4], loc[5]}} {
+			if group[0] >= 0 {
+				wind.Gust, _ = strconv.Atoi(windSpeed[group[0]:group[1]])
+			}
+		}
+		sustained = windSpeed[:loc[0]]
+	}
+
+	match := windSpeedRangeRegex.FindStringSubmatch(sustained)
+	if match == nil {
+		return wind, false
+	}
+
+	wind.MinSpeed, _ = strconv.Atoi(match[1])
+	wind.MaxSpeed = wind.MinSpeed
+	if match[2] != "" {
+		wind.MaxSpeed, _ = strconv.Atoi(match[2])
+	}
+
+	return wind, true
+}
+
+// MaxWindSpeed returns the highest sustained speed in an NWS wind speed string such as "5 to 10 mph". ok
+// is false when the string contains no speed
+func MaxWindSpeed(windSpeed string) (int, bool) {
+	wind, ok := ParseWind(windSpeed)
+	return wind.MaxSpeed, ok
+}
+
+// BeaufortScale is the label of each Beaufort wind force, indexed by force
+var BeaufortScale = []string{
+	"Calm",
+	"Light air",
+	"Light breeze",
+	"Gentle breeze",
+	"Moderate breeze",
+	"Fresh breeze",
+	"Strong breeze",
+	"Near gale",
+	"Gale",
+	"Strong gale",
+	"Storm",
+	"Violent storm",
+	"Hurricane force",
+}
+
+// beaufortUpperLimits is the highest wind speed in miles per hour of each Beaufort force below 12
+var beaufortUpperLimits = []int{0, 3, 7, 12, 18, 24, 31, 38, 46, 54, 63, 72}
+
+// BeaufortForce returns the Beaufort force of a sustained wind speed in miles per hour
+func BeaufortForce(mph int) int {
+	for force, limit := range beaufortUpperLimits {
+		if mph <= limit {
+			return force
+		}
+	}
+	return len(beaufortUpperLimits)
+}
+
+// Beaufort returns the Beaufort force and label of the highest sustained speed in an NWS wind speed
+// string. ok is false when the string contains no speed
+func Beaufort(windSpeed string) (int, string, bool) {
+	wind, ok := ParseWind(windSpeed)
+	if !ok {
+		return 0, "", false
+	}
+
+	force := BeaufortForce(wind.MaxSpeed)
+	return force, BeaufortScale[force], true
+}
@@ -0,0 +1,88 @@
+package nws
+
+import (
+	"testing"
+)
+
+func TestParseWind(t *testing.T) {
+	tests := []struct {
+		name      string
+		windSpeed string
+		want      Wind
+		wantOK    bool
+	}{
+		{name: "single speed", windSpeed: "5 mph", want: Wind{MinSpeed: 5, MaxSpeed: 5}, wantOK: true},
+		{name: "range", windSpeed: "10 to 15 mph", want: Wind{MinSpeed: 10, MaxSpeed: 15}, wantOK: true},
+		{name: "range with direction", windSpeed: "1 to 6 mph SW", want: Wind{MinSpeed: 1, MaxSpeed: 6}, wantOK: true},
+		{name: "gust code", windSpeed: "10 to 15 mph G25", want: Wind{MinSpeed: 10, MaxSpeed: 15, Gust: 25}, wantOK: true},
+		{name: "gusts text", windSpeed: "15 mph with gusts as high as 30 mph", want: Wind{MinSpeed: 15, MaxSpeed: 15, Gust: 30}, wantOK: true},
+		{name: "without units", windSpeed: "20", want: Wind{MinSpeed: 20, MaxSpeed: 20}, wantOK: true},
+		{name: "calm", windSpeed: "Calm", want: Wind{}, wantOK: true},
+		{name: "calm with whitespace", windSpeed: " calm ", want: Wind{}, wantOK: true},
+		{name: "empty", windSpeed: "", want: Wind{}, wantOK: false},
+		{name: "no speed", windSpeed: "light and variable", want: Wind{}, wantOK: false},
+		{name: "gust only", windSpeed: "G30", want: Wind{Gust: 30}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseWind(tt.windSpeed)
+			if ok != tt.wantOK {
+				t.Errorf("ParseWind(%q) ok = %v, want %v", tt.windSpeed, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWind(%q) = %+v, want %+v", tt.windSpeed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeaufortForce(t *testing.T) {
+	tests := []struct {
+		mph  int
+		want int
+	}{
+		{mph: 0, want: 0},
+		{mph: 1, want: 1},
+		{mph: 3, want: 1},
+		{mph: 4, want: 2},
+		{mph: 12, want: 3},
+		{mph: 13, want: 4},
+		{mph: 38, want: 7},
+		{mph: 39, want: 8},
+		{mph: 72, want: 11},
+		{mph: 73, want: 12},
+		{mph: 150, want: 12},
+	}
+
+	for _, tt := range tests {
+		if got := BeaufortForce(tt.mph); got != tt.want {
+			t.Errorf("BeaufortForce(%d) = %d, want %d", tt.mph, got, tt.want)
+		}
+	}
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		name      string
+		windSpeed string
+		wantForce int
+		wantLabel string
+		wantOK    bool
+	}{
+		{name: "calm", windSpeed: "Calm", wantForce: 0, wantLabel: "Calm", wantOK: true},
+		{name: "uses max speed", windSpeed: "5 to 15 mph", wantForce: 4, wantLabel: "Moderate breeze", wantOK: true},
+		{name: "ignores gusts", windSpeed: "10 mph G40", wantForce: 3, wantLabel: "Gentle breeze", wantOK: true},
+		{name: "hurricane force", windSpeed: "80 mph", wantForce: 12, wantLabel: "Hurricane force", wantOK: true},
+		{name: "no speed", windSpeed: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			force, label, ok := Beaufort(tt.windSpeed)
+			if ok != tt.wantOK || force != tt.wantForce || label != tt.wantLabel {
+				t.Errorf("Beaufort(%q) = %d, %q, %v, want %d, %q, %v", tt.windSpeed, force, label, ok, tt.wantForce, tt.wantLabel, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Names of the prompts, which are also the names of their template files without the .tmpl extension
+const (
+	ForecastSummary            = "forecast-summary"
+	ForecastPeriodsInformation = "forecast-periods-information"
+	ForecastChanges            = "forecast-changes"
+	HourlyForecastInformation  = "hourly-forecast-information"
+	ForecastDiscussion         = "forecast-discussion"
+	AlertExplanation           = "alert-explanation"
+	ConditionsComparison       = "conditions-comparison"
+)
+
+const templateExtension = ".tmpl"
+
+var (
+	ErrUnknownPrompt   = errors.New("unknown prompt")
+	ErrInvalidTemplate = errors.New("invalid prompt template")
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// Prompt is a rendered prompt, ready to be sent to an LLM
+type Prompt struct {
+	Name    string
+	Version string
+	System  string
+	User    string
+}
+
+// Data is what prompt templates are rendered with
+type Data struct {
+	// Input is the data the LLM is asked about, usually JSON
+	Input string
+
+	// Icons is the set of icons the LLM may choose from
+	Icons []string
+}
+
+// promptTemplate is a parsed template file, which defines the "version", "system" and "user" templates
+type promptTemplate struct {
+	template *template.Template
+	version  string
+	source   string
+}
+
+// Registry holds the prompt templates. The defaults are embedded in the binary, and any template in Dir
+// with the same name replaces its default, so prompts can be changed without a rebuild
+type Registry struct {
+	Dir string
+
+	mu        sync.RWMutex
+	templates map[string]promptTemplate
+}
+
+// NewRegistry creates a new prompt registry, loading the embedded templates and any overrides in dir.
+// An empty dir only uses the embedded templates
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{
+		Dir: dir,
+	}
+
+	err := r.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Load reads every template again, replacing the current templates only if all of them are valid
+func (r *Registry) Load() error {
+	defaults, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return fmt.Errorf("could not open embedded prompt templates: %w", err)
+	}
+
+	templates, err := parseTemplates(defaults, "embedded", nil)
+	if err != nil {
+		return err
+	}
+
+	if r.Dir != "" {
+		// fs.Glob ignores a missing directory, which is more likely a misconfiguration than no overrides
+		_, err = os.Stat(r.Dir)
+		if err != nil {
+			return fmt.Errorf("could not open prompt template directory: %w", err)
+		}
+
+		templates, err = parseTemplates(os.DirFS(r.Dir), r.Dir, templates)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	previous := r.templates
+	r.templates = templates
+	r.mu.Unlock()
+
+	for name, t := range templates {
+		if p, ok := previous[name]; ok && p.version != t.version {
+			slog.Info("prompt version changed", slog.String("prompt", name), slog.String("previous_version", p.version), slog.String("version", t.version))
+		} else if ok && p.source != t.source {
+			slog.Warn("prompt template changed without a version change", slog.String("prompt", name), slog.String("version", t.version))
+		}
+	}
+
+	return nil
+}
+
+// parseTemplates parses every template file in fsys. With defaults, only prompts that already have a
+// default may be overridden, so a misnamed file is an error rather than silently ignored
+func parseTemplates(fsys fs.FS, location string, defaults map[string]promptTemplate) (map[string]promptTemplate, error) {
+	files, err := fs.Glob(fsys, "*"+templateExtension)
+	if err != nil {
+		return nil, fmt.Errorf("could not list prompt templates in %s: %w", location, err)
+	}
+
+	templates := make(map[string]promptTemplate, len(defaults)+len(files))
+	for name, t := range defaults {
+		templates[name] = t
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), templateExtension)
+		if defaults != nil {
+			if _, ok := defaults[name]; !ok {
+				return nil, fmt.Errorf("%w: %s in %s", ErrUnknownPrompt, name, location)
+			}
+		}
+
+		source, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read prompt template %s in %s: %w", name, location, err)
+		}
+
+		t, err := parseTemplate(name, string(source))
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", name, location, err)
+		}
+
+		templates[name] = t
+	}
+
+	return templates, nil
+}
+
+func parseTemplate(name string, source string) (promptTemplate, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return promptTemplate{}, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	for _, required := range []string{"version", "system", "user"} {
+		if t.Lookup(required) == nil {
+			return promptTemplate{}, fmt.Errorf("%w: missing %q template", ErrInvalidTemplate, required)
+		}
+	}
+
+	version, err := execute(t, "version", nil)
+	if err != nil {
+		return promptTemplate{}, err
+	}
+	if version == "" {
+		return promptTemplate{}, fmt.Errorf("%w: empty version", ErrInvalidTemplate)
+	}
+
+	// rendering with empty data catches references to fields Data does not have before the template is used
+	for _, prompt := range []string{"system", "user"} {
+		_, err := execute(t, prompt, Data{})
+		if err != nil {
+			return promptTemplate{}, err
+		}
+	}
+
+	return promptTemplate{
+		template: t,
+		version:  version,
+		source:   source,
+	}, nil
+}
+
+func execute(t *template.Template, name string, data any) (string, error) {
+	var buf bytes.Buffer
+	err := t.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Render renders the system and user prompts of a template
+func (r *Registry) Render(name string, data Data) (Prompt, error) {
+	r.mu.RLock()
+	t, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Prompt{}, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+
+	system, err := execute(t.template, "system", data)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to render %s system prompt: %w", name, err)
+	}
+
+	user, err := execute(t.template, "user", data)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to render %s user prompt: %w", name, err)
+	}
+
+	return Prompt{
+		Name:    name,
+		Version: t.version,
+		System:  system,
+		User:    user,
+	}, nil
+}
+
+// Version returns the current version of a prompt, or an empty string for an unknown prompt
+func (r *Registry) Version(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.templates[name].version
+}
+
+// Versions returns the current versions of several prompts as a single string, e.g.
+// "forecast-periods-information@1,forecast-summary@2", for detecting when any of them changed
+func (r *Registry) Versions(names ...string) string {
+	sorted := slices.Clone(names)
+	slices.Sort(sorted)
+
+	versions := make([]string, 0, len(sorted))
+	for _, name := range sorted {
+		versions = append(versions, fmt.Sprintf("%s@%s", name, r.Version(name)))
+	}
+	return strings.Join(versions, ",")
+}
+
+// Watch reloads the templates every interval until ctx is done. A template that fails to load is logged
+// and the previous templates are kept
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Load()
+			if err != nil {
+				slog.Error("could not reload prompt templates, keeping the previous templates", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
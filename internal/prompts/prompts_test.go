@@ -0,0 +1,215 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var promptNames = []string{
+	ForecastSummary,
+	ForecastPeriodsInformation,
+	ForecastChanges,
+	HourlyForecastInformation,
+	ForecastDiscussion,
+	AlertExplanation,
+	ConditionsComparison,
+}
+
+// testTemplate returns a valid template source with the given version
+func testTemplate(version string) string {
+	return `{{define "version"}}` + version + `{{end}}
+{{define "system"}}test system prompt{{end}}
+{{define "user"}}input: {{.Input}}{{end}}`
+}
+
+func writeTemplate(t *testing.T, dir string, name string, source string) {
+	t.Helper()
+
+	err := os.WriteFile(filepath.Join(dir, name+templateExtension), []byte(source), 0o600)
+	if err != nil {
+		t.Fatalf("could not write template: %v", err)
+	}
+}
+
+func TestNewRegistryEmbedded(t *testing.T) {
+	r, err := NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	for _, name := range promptNames {
+		t.Run(name, func(t *testing.T) {
+			if r.Version(name) == "" {
+				t.Errorf("Version() is empty")
+			}
+
+			prompt, err := r.Render(name, Data{Input: "test-input", Icons: []string{"sun"}})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if prompt.Name != name || prompt.Version != r.Version(name) {
+				t.Errorf("Render() = %q version %q, want %q version %q", prompt.Name, prompt.Version, name, r.Version(name))
+			}
+			if prompt.System == "" || !strings.Contains(prompt.User, "test-input") {
+				t.Errorf("Render() system = %q, user = %q, want a system prompt and the input in the user prompt", prompt.System, prompt.User)
+			}
+		})
+	}
+
+	if _, err := r.Render("unknown", Data{}); !errors.Is(err, ErrUnknownPrompt) {
+		t.Errorf("Render() error = %v, want %v", err, ErrUnknownPrompt)
+	}
+	if r.Version("unknown") != "" {
+		t.Errorf("Version() = %q for an unknown prompt, want empty", r.Version("unknown"))
+	}
+}
+
+func TestNewRegistryOverride(t *testing.T) {
+	embedded, err := NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	writeTemplate(t, dir, ForecastChanges, testTemplate("override"))
+
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	if r.Version(ForecastChanges) != "override" {
+		t.Errorf("Version() = %q, want %q", r.Version(ForecastChanges), "override")
+	}
+	prompt, err := r.Render(ForecastChanges, Data{Input: "changes"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if prompt.System != "test system prompt" || prompt.User != "input: changes" {
+		t.Errorf("Render() system = %q, user = %q, want the override", prompt.System, prompt.User)
+	}
+
+	if r.Version(ForecastSummary) != embedded.Version(ForecastSummary) {
+		t.Errorf("Version() = %q for a prompt without an override, want the embedded %q", r.Version(ForecastSummary), embedded.Version(ForecastSummary))
+	}
+}
+
+func TestNewRegistryInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		source  string
+		wantErr error
+	}{
+		{
+			name:    "does not parse",
+			file:    ForecastChanges,
+			source:  `{{define "version"}}1{{end}}{{define "system"}}{{.Input{{end}}{{define "user"}}{{end}}`,
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name:    "missing user template",
+			file:    ForecastChanges,
+			source:  `{{define "version"}}1{{end}}{{define "system"}}system{{end}}`,
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name:    "empty version",
+			file:    ForecastChanges,
+			source:  `{{define "version"}} {{end}}{{define "system"}}system{{end}}{{define "user"}}{{.Input}}{{end}}`,
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name:    "does not execute",
+			file:    ForecastChanges,
+			source:  `{{define "version"}}1{{end}}{{define "system"}}system{{end}}{{define "user"}}{{.Forecast}}{{end}}`,
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name:    "unknown prompt",
+			file:    "forecast-change",
+			source:  testTemplate("1"),
+			wantErr: ErrUnknownPrompt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, tt.file, tt.source)
+
+			_, err := NewRegistry(dir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewRegistry() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := NewRegistry(filepath.Join(t.TempDir(), "missing"))
+		if err == nil {
+			t.Errorf("NewRegistry() error = nil, want an error for a missing directory")
+		}
+	})
+}
+
+func TestLoadKeepsTemplatesOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, ForecastChanges, testTemplate("1"))
+
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	writeTemplate(t, dir, ForecastChanges, `{{define "version"}}2{{end}}{{define "system"}}`)
+	if err := r.Load(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Fatalf("Load() error = %v, want %v", err, ErrInvalidTemplate)
+	}
+	if r.Version(ForecastChanges) != "1" {
+		t.Errorf("Version() = %q after a failed load, want %q", r.Version(ForecastChanges), "1")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, ForecastChanges, testTemplate("1"))
+
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	versions := r.Versions(ForecastSummary, ForecastChanges)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Watch(ctx, 5*time.Millisecond)
+	}()
+
+	writeTemplate(t, dir, ForecastChanges, testTemplate("2"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Version(ForecastChanges) != "2" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if r.Version(ForecastChanges) != "2" {
+		t.Errorf("Version() = %q after the template changed, want %q", r.Version(ForecastChanges), "2")
+	}
+	if r.Versions(ForecastSummary, ForecastChanges) == versions {
+		t.Errorf("Versions() = %q did not change", versions)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch() did not return after its context was cancelled")
+	}
+}
@@ -3,447 +3,641 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/dragonfly"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/cache"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/generator"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/history"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/leader"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/llm"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
 	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/prompts"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
-// ForecastWorker handles background generation of forecast data
+const meterName = "github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/worker"
+
+// Reasons a run was scheduled
+const (
+	RunReasonStartup      = "startup"
+	RunReasonPoll         = "poll"
+	RunReasonMaxStaleness = "max_staleness"
+)
+
+// Reasons a location was regenerated
+const (
+	RegenerateReasonForecastUpdated = "forecast_updated"
+	RegenerateReasonCacheMissing    = "cache_missing"
+	RegenerateReasonCheckFailed     = "check_failed"
+	RegenerateReasonMaxStaleness    = "max_staleness"
+	RegenerateReasonPromptChanged   = "prompt_changed"
+)
+
+// workerPrompts are the prompts of the products the worker generates, which are regenerated when any
+// of their versions changes
+var workerPrompts = []string{prompts.ForecastSummary, prompts.ForecastPeriodsInformation}
+
+// ForecastWorker handles background generation of forecast data. Rather than regenerating on a fixed
+// interval, it polls NWS every PollInterval and only regenerates a location once its forecast has been
+// updated or its cached products are older than MaxStaleness
 type ForecastWorker struct {
-	LLMProvider     llm.Provider
-	NWSClient       *nws.NWSClient
-	DragonflyClient *dragonfly.DragonflyClient
-	Interval        time.Duration
-	Timeout         time.Duration
-	GridPoint       string
+	LLMProvider  llm.Provider
+	Prompts      *prompts.Registry
+	Generator    *generator.Generator
+	NWSClient    *nws.NWSClient
+	Cache        *cache.Cache
+	History      *history.Archive
+	Registry     *locations.Registry
+	PollInterval time.Duration
+	MaxStaleness time.Duration
+	Timeout      time.Duration
+	Concurrency  int
+
+	generationsCounter metric.Int64Counter
+
+	statusMu      sync.RWMutex
+	running       bool
+	lastRun       []LocationResult
+	lastRunAt     time.Time
+	lastRunReason string
+	nextRunAt     time.Time
+	nextRunReason string
+	generatedAt   map[string]time.Time
+}
+
+// LocationResult reports the outcome of generating forecasts for a single location
+type LocationResult struct {
+	Location      locations.Location `json:"location"`
+	Reason        string             `json:"reason,omitempty"`
+	SummaryError  string             `json:"summary_error,omitempty"`
+	DetailedError string             `json:"detailed_error,omitempty"`
+	Success       bool               `json:"success"`
+	Skipped       bool               `json:"skipped"`
+	GeneratedAt   time.Time          `json:"generated_at"`
+	CompletedAt   time.Time          `json:"completed_at"`
+}
+
+// Status describes the worker's most recent and next scheduled runs
+type Status struct {
+	Running       bool             `json:"running"`
+	PollInterval  string           `json:"poll_interval"`
+	MaxStaleness  string           `json:"max_staleness"`
+	LastRunAt     time.Time        `json:"last_run_at"`
+	LastRunReason string           `json:"last_run_reason"`
+	NextRunAt     time.Time        `json:"next_run_at"`
+	NextRunReason string           `json:"next_run_reason"`
+	Locations     []LocationResult `json:"locations"`
+}
+
+// forecastState is what the cached products for a grid point were generated from
+type forecastState struct {
+	Version        nws.ForecastVersion `json:"version"`
+	PromptVersions string              `json:"prompt_versions"`
+	GeneratedAt    time.Time           `json:"generated_at"`
+}
+
+// forecastCheck is what polling NWS found out about a grid point's forecast
+type forecastCheck struct {
+	// previous is the state the cached products were generated from
+	previous forecastState
+	// previousReason is why the location needs regenerating when previous could not be read
+	previousReason string
+	// current is the latest forecast version, and modified is false when NWS reported it unchanged
+	current  nws.ForecastVersion
+	modified bool
+	// err is the error polling NWS, if any
+	err error
+}
+
+// regenerateReason decides why a location needs regenerating, or returns an empty reason if its cached
+// products can be kept because the forecast is unchanged, they were generated with promptVersions and they
+// are younger than maxStaleness
+func (c forecastCheck) regenerateReason(promptVersions string, maxStaleness time.Duration, now time.Time) string {
+	switch {
+	case c.err != nil:
+		return RegenerateReasonCheckFailed
+	case c.previousReason != "":
+		return c.previousReason
+	case c.modified && !c.current.SameForecast(c.previous.Version):
+		return RegenerateReasonForecastUpdated
+	case c.previous.PromptVersions != promptVersions:
+		return RegenerateReasonPromptChanged
+	case now.Sub(c.previous.GeneratedAt) >= maxStaleness:
+		return RegenerateReasonMaxStaleness
+	}
+
+	return ""
 }
 
 // NewForecastWorker creates a new forecast worker
 func NewForecastWorker(
 	provider llm.Provider,
+	promptRegistry *prompts.Registry,
 	nwsClient *nws.NWSClient,
-	dragonflyClient *dragonfly.DragonflyClient,
-	interval time.Duration,
+	c *cache.Cache,
+	archive *history.Archive,
+	registry *locations.Registry,
+	pollInterval time.Duration,
+	maxStaleness time.Duration,
 	timeout time.Duration,
-	gridPoint string,
-) *ForecastWorker {
-	return &ForecastWorker{
-		LLMProvider:     provider,
-		NWSClient:       nwsClient,
-		DragonflyClient: dragonflyClient,
-		Interval:        interval,
-		Timeout:         timeout,
-		GridPoint:       gridPoint,
+	concurrency int,
+) (*ForecastWorker, error) {
+	if concurrency < 1 {
+		concurrency = 1
 	}
+
+	generationsCounter, err := otel.Meter(meterName).Int64Counter(
+		"worker.generations",
+		metric.WithDescription("number of forecast products generated by the worker, by location, product and status"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create generations counter: %w", err)
+	}
+
+	gen, err := generator.NewGenerator(provider, promptRegistry, nwsClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForecastWorker{
+		LLMProvider:        provider,
+		Prompts:            promptRegistry,
+		Generator:          gen,
+		NWSClient:          nwsClient,
+		Cache:              c,
+		History:            archive,
+		Registry:           registry,
+		PollInterval:       pollInterval,
+		MaxStaleness:       maxStaleness,
+		Timeout:            timeout,
+		Concurrency:        concurrency,
+		generationsCounter: generationsCounter,
+		generatedAt:        make(map[string]time.Time),
+	}, nil
 }
 
-// Start begins the background worker loop
+// Start begins the background worker loop, returning once ctx is done. With leader election, Start is only
+// called on the leader and all writes are fenced by its lease
 func (w *ForecastWorker) Start(ctx context.Context) {
-	slog.Info("starting forecast worker", slog.Duration("interval", w.Interval))
-
-	// Run immediately on start
-	w.runGeneration(ctx)
+	w.setRunning(true)
+	defer w.setRunning(false)
 
-	ticker := time.NewTicker(w.Interval)
-	defer ticker.Stop()
+	slog.Info("starting forecast worker",
+		slog.Duration("poll_interval", w.PollInterval),
+		slog.Duration("max_staleness", w.MaxStaleness),
+		slog.Int("concurrency", w.Concurrency),
+	)
 
+	reason := RunReasonStartup
 	for {
+		w.runGeneration(ctx, reason)
+
+		var next time.Time
+		next, reason = w.scheduleNextRun()
+		slog.Info("next forecast generation scheduled", slog.Time("at", next), slog.String("reason", reason))
+
+		timer := time.NewTimer(time.Until(next))
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			slog.Info("stopping forecast worker")
 			return
-		case <-ticker.C:
-			w.runGeneration(ctx)
+		case <-timer.C:
 		}
 	}
 }
 
-// runGeneration runs both forecast summary and detailed generation
-func (w *ForecastWorker) runGeneration(ctx context.Context) {
-	slog.Info("running forecast generation")
+func (w *ForecastWorker) setRunning(running bool) {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
 
-	// Run both generations concurrently
-	done := make(chan struct{}, 2)
+	w.running = running
+	if !running {
+		w.nextRunAt = time.Time{}
+		w.nextRunReason = ""
+	}
+}
 
-	go func() {
-		w.generateForecastSummary(ctx)
-		done <- struct{}{}
-	}()
+// set writes a key, fenced by the leader lease when running under leader election
+func (w *ForecastWorker) set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if lease := leader.LeaseFromContext(ctx); lease != nil {
+		return lease.Set(ctx, key, value, expiration)
+	}
 
-	go func() {
-		w.generateForecastPeriodsInformation(ctx)
-		done <- struct{}{}
-	}()
+	return w.Cache.Set(ctx, key, value, expiration)
+}
 
-	// Wait for both to complete
-	<-done
-	<-done
+// scheduleNextRun picks the next poll, or an earlier run if a location would otherwise exceed MaxStaleness
+func (w *ForecastWorker) scheduleNextRun() (time.Time, string) {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
 
-	slog.Info("forecast generation complete")
+	next, reason := nextRun(time.Now(), w.PollInterval, w.MaxStaleness, w.generatedAt)
+	w.nextRunAt = next
+	w.nextRunReason = reason
+	return next, reason
 }
 
-// ForecastSummaryResponse matches the handler response structure
-type ForecastSummaryResponse struct {
-	Summary     string    `json:"summary"`
-	Icon        string    `json:"icon"`
-	LastUpdated time.Time `json:"last_updated"`
+// nextRun returns when the run after now should be and why, given when each grid point's products were
+// generated
+func nextRun(now time.Time, pollInterval time.Duration, maxStaleness time.Duration, generatedAt map[string]time.Time) (time.Time, string) {
+	next, reason := now.Add(pollInterval), RunReasonPoll
+	for _, generatedAt := range generatedAt {
+		// locations already past their deadline failed to regenerate, so they are retried on the next poll
+		if deadline := generatedAt.Add(maxStaleness); deadline.After(now) && deadline.Before(next) {
+			next, reason = deadline, RunReasonMaxStaleness
+		}
+	}
+
+	return next, reason
 }
 
-func (w *ForecastWorker) generateForecastSummary(ctx context.Context) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
-	defer cancel()
+// LastRun returns the per-location results of the most recent generation run
+func (w *ForecastWorker) LastRun() []LocationResult {
+	w.statusMu.RLock()
+	defer w.statusMu.RUnlock()
 
-	periods, err := w.NWSClient.GetSimplifiedForecastNPeriods(w.GridPoint, 3)
-	if err != nil {
-		slog.Error("worker: failed to get simplified forecast periods", slog.String("error", err.Error()))
-		return
+	return w.lastRun
+}
+
+// Status returns the worker's most recent and next scheduled runs
+func (w *ForecastWorker) Status() Status {
+	w.statusMu.RLock()
+	defer w.statusMu.RUnlock()
+
+	return Status{
+		Running:       w.running,
+		PollInterval:  w.PollInterval.String(),
+		MaxStaleness:  w.MaxStaleness.String(),
+		LastRunAt:     w.lastRunAt,
+		LastRunReason: w.lastRunReason,
+		NextRunAt:     w.nextRunAt,
+		NextRunReason: w.nextRunReason,
+		Locations:     w.lastRun,
 	}
+}
 
-	periodsJSON, err := json.Marshal(periods)
+// runGeneration checks every registered location and regenerates those that need it, with at most
+// Concurrency locations in flight at once
+func (w *ForecastWorker) runGeneration(ctx context.Context, reason string) {
+	locs, err := w.Registry.List(ctx)
 	if err != nil {
-		slog.Error("worker: failed to marshal simplified forecast periods", slog.String("error", err.Error()))
+		slog.Error("worker: failed to list locations", slog.String("error", err.Error()))
 		return
 	}
 
-	systemPrompt := `You are a tool that can provide concise summaries of weather forecasts.
-	You have access to the following list of icons:
-	"""
-	cloud
-	cloud-drizzle
-	cloud-fog
-	cloud-hail
-	cloud-lightning
-	cloud-moon
-	cloud-moon-rain
-	cloud-rain
-	cloud-rain-wind
-	cloud-snow
-	cloud-sun
-	cloud-sun-rain
-	cloudy
-	snowflake
-	sun
-	sun-snow
-	thermometer-snowflake
-	thermometer-sun
-	wind
-	"""
-	`
-
-	prompt := `
-		Input is a JSON array with one entry per forecast period.
-		Output is a JSON object with the key "summary" containing the overall forecast in at most four sentences and "icon" containing the icon that best fits the soonest weather for this summary.
-		Each entry contains relavant weather information including a detailed text forecast.
-		Do not include any information that is not present in the input.
-		Do not comment twice on the same weather condition.
-		Focus mainly on the daytime periods.
-		Avoid editorializing or making assumptions.
-		Avoid referring to "periods" in the output.
-		Make the output sound like a human wrote it, with concise but friendly language and complete sentences.`
-
-	fewShotTraining := []multiShot{
-		{
-			Input: `[
-					{
-						"name": "Tonight",
-						"start_time": "2024-06-08T20:00:00-07:00",
-						"end_time": "2024-06-09T06:00:00-07:00",
-						"temperature": "54F",
-						"detailed_forecast": "Mostly cloudy, with a low around 54. East wind around 2 mph.",
-						"relative_humidity": "80%",
-						"wind_speed": "2 mph E"
-						},
-					}
-					{
-						"name": "Sunday",
-						"start_time": "2024-06-09T06:00:00-07:00",
-						"end_time": "2024-06-09T18:00:00-07:00",
-						"temperature": "74F",
-						"detailed_forecast": "Mostly sunny. High near 74, with temperatures falling to around 72 in the afternoon. Southwest wind 1 to 6 mph.",
-						"relative_humidity": "79%",
-						"wind_speed": "1 to 6 mph SW"
-					},
-					{
-						"name": "Sunday Night",
-						"start_time": "2024-06-09T18:00:00-07:00",
-						"end_time": "2024-06-10T06:00:00-07:00",
-						"temperature": "51F",
-						"detailed_forecast": "Mostly cloudy, with a low around 51. West wind 2 to 6 mph.",
-						"relative_humidity": "85%",
-						"wind_speed": "2 to 6 mph W"
-					}
-				]`,
-			Output: `{"summary": "Tonight, mostly cloudy with a low around 54. Sunday, mostly sunny with a high near 74, temperatures falling to around 72 in the afternoon. Sunday night, mostly cloudy with a low around 51. Winds light and variable.", "icon": "cloud-moon"}`,
-		},
-	}
-
-	response, err := w.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
-		SystemPrompt: systemPrompt,
-		UserPrompt:   buildFinalPrompt(prompt, fewShotTraining, string(periodsJSON)),
-		MaxTokens:    4096,
-	})
-	if err != nil {
-		slog.Error("worker: failed to get forecast summary", slog.String("error", err.Error()))
-		return
+	slog.Info("running forecast generation", slog.Int("locations", len(locs)), slog.String("reason", reason))
+
+	results := make([]LocationResult, len(locs))
+	semaphore := make(chan struct{}, w.Concurrency)
+
+	var wg sync.WaitGroup
+	for i, location := range locs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = w.generateLocation(ctx, location)
+		}()
 	}
+	wg.Wait()
 
-	var fsr ForecastSummaryResponse
-	cleanedText := stripMarkdownCodeBlock(response.Content)
-	err = json.Unmarshal([]byte(cleanedText), &fsr)
-	if err != nil {
-		slog.Error("worker: failed to unmarshal forecast summary", slog.String("error", err.Error()), slog.String("response", cleanedText))
-		return
+	succeeded, skipped, generatedAt := summarizeRun(results)
+
+	w.statusMu.Lock()
+	w.lastRun = results
+	w.lastRunAt = time.Now()
+	w.lastRunReason = reason
+	w.generatedAt = generatedAt
+	w.statusMu.Unlock()
+
+	slog.Info("forecast generation complete", slog.Int("succeeded", succeeded), slog.Int("skipped", skipped), slog.Int("failed", len(results)-succeeded))
+}
+
+// summarizeRun counts the locations of a run that succeeded and were skipped, and returns when each grid
+// point's products were generated for scheduling the next run
+func summarizeRun(results []LocationResult) (int, int, map[string]time.Time) {
+	succeeded, skipped := 0, 0
+	generatedAt := make(map[string]time.Time, len(results))
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+		if result.Skipped {
+			skipped++
+		}
+		if !result.GeneratedAt.IsZero() {
+			generatedAt[result.Location.GridPoint.String()] = result.GeneratedAt
+		}
 	}
 
-	fsr.LastUpdated = time.Now()
+	return succeeded, skipped, generatedAt
+}
 
-	fsrJson, err := json.Marshal(fsr)
-	if err != nil {
-		slog.Error("worker: failed to marshal forecast summary", slog.String("error", err.Error()))
-		return
+// generateLocation runs both forecast summary and detailed generation for a single location, unless
+// NWS has not issued a new forecast since the cached products were generated, they were generated with
+// the current prompts and they are not yet stale
+func (w *ForecastWorker) generateLocation(ctx context.Context, location locations.Location) LocationResult {
+	promptVersions := w.Prompts.Versions(workerPrompts...)
+
+	check := w.checkForecast(ctx, location.GridPoint)
+	previous, current := check.previous, check.current
+
+	reason := check.regenerateReason(promptVersions, w.MaxStaleness, time.Now())
+
+	if reason == "" {
+		if w.extendProducts(ctx, location.GridPoint) {
+			w.setForecastState(ctx, location.GridPoint, forecastState{Version: current, PromptVersions: promptVersions, GeneratedAt: previous.GeneratedAt})
+			w.recordSkipped(ctx, location)
+			return LocationResult{
+				Location:    location,
+				Success:     true,
+				Skipped:     true,
+				GeneratedAt: previous.GeneratedAt,
+				CompletedAt: time.Now(),
+			}
+		}
+		reason = RegenerateReasonCacheMissing
 	}
 
-	err = w.DragonflyClient.Client.Set(timeoutCtx, fmt.Sprintf("%s-%s", w.DragonflyClient.KeyPrefix, "forecast-summary"), fsrJson, w.DragonflyClient.CacheResultsDuration).Err()
-	if err != nil {
-		slog.Error("worker: could not set forecast summary in cache", slog.String("error", err.Error()))
-		return
+	slog.Info("worker: regenerating forecast",
+		slog.String("location", location.Name),
+		slog.String("grid_point", location.GridPoint.String()),
+		slog.String("reason", reason),
+	)
+
+	var summaryErr, detailedErr error
+
+	// Run both generations concurrently
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		summaryErr = w.generateForecastSummary(ctx, location.GridPoint)
+		w.recordGeneration(ctx, location, "summary", summaryErr)
+	}()
+
+	go func() {
+		defer wg.Done()
+		detailedErr = w.generateForecastPeriodsInformation(ctx, location.GridPoint)
+		w.recordGeneration(ctx, location, "detailed", detailedErr)
+	}()
+
+	wg.Wait()
+
+	result := newLocationResult(location, reason, previous.GeneratedAt, summaryErr, detailedErr, time.Now())
+	if result.Success {
+		w.setForecastState(ctx, location.GridPoint, forecastState{Version: current, PromptVersions: promptVersions, GeneratedAt: result.GeneratedAt})
 	}
 
-	slog.Info("worker: forecast summary generated and cached")
+	return result
 }
 
-// GetForecastPeriodsInformation matches the handler structure
-type GetForecastPeriodsInformation struct {
-	Name      string `json:"name"`
-	TimeOfDay string `json:"time_of_day"`
-	Icon      string `json:"icon"`
-	Beaufort  string `json:"beaufort"`
+// newLocationResult reports regenerating a location at now. A location that failed keeps the time its
+// products were previously generated, so it is still scheduled by when they go stale
+func newLocationResult(location locations.Location, reason string, previousGeneratedAt time.Time, summaryErr error, detailedErr error, now time.Time) LocationResult {
+	result := LocationResult{
+		Location:    location,
+		Reason:      reason,
+		Success:     summaryErr == nil && detailedErr == nil,
+		GeneratedAt: previousGeneratedAt,
+		CompletedAt: now,
+	}
+
+	if result.Success {
+		result.GeneratedAt = now
+	}
+
+	if summaryErr != nil {
+		result.SummaryError = summaryErr.Error()
+	}
+
+	if detailedErr != nil {
+		result.DetailedError = detailedErr.Error()
+	}
+
+	return result
+}
+
+// recordGeneration logs and counts the outcome of generating a single product for a location
+func (w *ForecastWorker) recordGeneration(ctx context.Context, location locations.Location, product string, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+		slog.Error("worker: forecast generation failed",
+			slog.String("location", location.Name),
+			slog.String("grid_point", location.GridPoint.String()),
+			slog.String("product", product),
+			slog.String("error", err.Error()),
+		)
+	} else {
+		slog.Info("worker: forecast generated and cached",
+			slog.String("location", location.Name),
+			slog.String("grid_point", location.GridPoint.String()),
+			slog.String("product", product),
+		)
+	}
+
+	w.generationsCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("location", location.Name),
+		attribute.String("product", product),
+		attribute.String("status", status),
+	))
 }
 
-// JoinedForecastPeriodsInformation matches the handler structure
-type JoinedForecastPeriodsInformation struct {
-	Name             string    `json:"name"`
-	TimeOfDay        string    `json:"time_of_day"`
-	Icon             string    `json:"icon"`
-	Beaufort         string    `json:"beaufort"`
-	DetailedForecast string    `json:"detailed_forecast"`
-	ShortForecast    string    `json:"short_forecast"`
-	StartTime        time.Time `json:"start_time"`
-	EndTime          time.Time `json:"end_time"`
-	Temperature      int       `json:"temperature"`
-	WindSpeed        string    `json:"wind_speed"`
-	WindDirection    string    `json:"wind_direction"`
+// recordSkipped logs and counts a location whose products were left alone because its forecast is unchanged
+func (w *ForecastWorker) recordSkipped(ctx context.Context, location locations.Location) {
+	slog.Info("worker: forecast unchanged, skipping generation",
+		slog.String("location", location.Name),
+		slog.String("grid_point", location.GridPoint.String()),
+	)
+
+	for _, product := range []string{"summary", "detailed"} {
+		w.generationsCounter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("location", location.Name),
+			attribute.String("product", product),
+			attribute.String("status", "skipped_unchanged"),
+		))
+	}
 }
 
-// GetForecastPeriodsInformationResponse matches the handler structure
-type GetForecastPeriodsInformationResponse struct {
-	Periods     []JoinedForecastPeriodsInformation `json:"periods"`
-	LastUpdated time.Time                          `json:"last_updated"`
+// checkForecast polls NWS for the grid point's forecast with a conditional request against the version the
+// cached products were generated from
+func (w *ForecastWorker) checkForecast(ctx context.Context, gridPoint nws.GridPoint) forecastCheck {
+	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	var check forecastCheck
+
+	res, err := w.Cache.Get(timeoutCtx, w.Cache.Key("forecast-state", gridPoint.String()))
+	if errors.Is(err, cache.ErrNotFound) {
+		check.previousReason = RegenerateReasonCacheMissing
+	} else if err != nil {
+		slog.Error("worker: could not get forecast state from cache", slog.String("error", err.Error()))
+		check.previousReason = RegenerateReasonCheckFailed
+	} else if err := json.Unmarshal(res, &check.previous); err != nil {
+		slog.Error("worker: could not unmarshal forecast state", slog.String("error", err.Error()))
+		check.previousReason = RegenerateReasonCacheMissing
+	}
+
+	// the current version is fetched even when regenerating anyway, so it can be recorded afterwards
+	_, check.current, check.modified, check.err = w.NWSClient.GetForecastIfModified(timeoutCtx, gridPoint.String(), check.previous.Version)
+	return check
 }
 
-func (w *ForecastWorker) generateForecastPeriodsInformation(ctx context.Context) {
+// extendProducts marks both cached products for a grid point fresh again, reporting false if either is no
+// longer cached
+func (w *ForecastWorker) extendProducts(ctx context.Context, gridPoint nws.GridPoint) bool {
 	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
 	defer cancel()
 
-	periods, err := w.NWSClient.GetSimplifiedForecastNPeriods(w.GridPoint, -1)
+	productKeys := []string{
+		w.Cache.Key("forecast-summary", gridPoint.String()),
+		w.Cache.Key("forecast-periods-information", gridPoint.String()),
+	}
+	for _, key := range productKeys {
+		res, err := w.Cache.Get(timeoutCtx, key)
+		if errors.Is(err, cache.ErrNotFound) {
+			return false
+		} else if err != nil {
+			slog.Error("worker: could not get cached product", slog.String("error", err.Error()), slog.String("key", key))
+			return false
+		}
+
+		entry, err := cache.ParseEntry(res)
+		if err != nil {
+			slog.Error("worker: could not parse cached product", slog.String("error", err.Error()), slog.String("key", key))
+			return false
+		}
+
+		// StoredAt is kept so the Age reported to clients still reflects when the product was generated
+		entry.FreshUntil = time.Now().Add(w.Cache.CacheResultsDuration)
+
+		err = w.setEntry(timeoutCtx, key, entry)
+		if err != nil {
+			slog.Error("worker: could not extend cached product", slog.String("error", err.Error()), slog.String("key", key))
+			return false
+		}
+	}
+
+	return true
+}
+
+// setEntry writes a cache entry, keeping it for CacheStaleDuration after it stops being fresh, along with a
+// non-expiring last known good copy for handlers to fall back on
+func (w *ForecastWorker) setEntry(ctx context.Context, key string, entry cache.Entry) error {
+	entryJSON, err := entry.Marshal()
 	if err != nil {
-		slog.Error("worker: failed to get simplified forecast periods", slog.String("error", err.Error()))
-		return
+		return err
 	}
 
-	periodsJSON, err := json.Marshal(periods)
+	err = w.set(ctx, key, entryJSON, w.Cache.CacheResultsDuration+w.Cache.CacheStaleDuration)
 	if err != nil {
-		slog.Error("worker: failed to marshal simplified forecast periods", slog.String("error", err.Error()))
+		return err
+	}
+
+	return w.set(ctx, cache.LastKnownGoodKey(key), entryJSON, 0)
+}
+
+// setForecastState records the forecast and prompt versions the cached products for a grid point were
+// generated from
+func (w *ForecastWorker) setForecastState(ctx context.Context, gridPoint nws.GridPoint, state forecastState) {
+	if state.Version.UpdateTime.IsZero() {
 		return
 	}
 
-	systemPrompt := `You are a tool that can provide concise weather forecast breakdowns.
-		You have access to the following list of icons:
-		"""
-		cloud
-		cloud-drizzle
-		cloud-fog
-		cloud-hail
-		cloud-lightning
-		cloud-moon
-		cloud-moon-rain
-		cloud-rain
-		cloud-rain-wind
-		cloud-snow
-		cloud-sun
-		cloud-sun-rain
-		cloudy
-		snowflake
-		sun
-		sun-snow
-		thermometer-snowflake
-		thermometer-sun
-		wind
-		"""
-		`
-
-	prompt := `Input is a JSON array with one entry per forecast period.
-		Output is a JSON array with the following key-value pairs:
-		"name": the "name" field on the given forecast period,
-		"time_of_day": either day or night based upon the given forecast period,
-		"icon": the icon that best fits the "detailed_forecast" for this forecast period,
-		"beaufort": the beaufort scale string that best fits the "wind_speed" for this period,
-
-		Do not include any information that is not present in the input.
-		Only include the JSON, do not include outside text.
-
-
-		Structure the output exactly like this, but remove all whitespace:
-
-		"""
-		[
-		{
-			"name": "",
-			"time_of_day": "",
-			"icon": "",
-			"beaufort": "",
-		},
-		...
-		]
-		"""
-		`
-
-	fewShotTraining := []multiShot{
-		{
-			Input: `[
-					{
-						"name": "Tonight",
-						"start_time": "2024-06-08T20:00:00-07:00",
-						"end_time": "2024-06-09T06:00:00-07:00",
-						"temperature": "54F",
-						"detailed_forecast": "Mostly cloudy, with a low around 54. East wind around 2 mph.",
-						"relative_humidity": "80%",
-						"wind_speed": "2 mph E"
-						},
-					}
-					{
-						"name": "Sunday",
-						"start_time": "2024-06-09T06:00:00-07:00",
-						"end_time": "2024-06-09T18:00:00-07:00",
-						"temperature": "74F",
-						"detailed_forecast": "Mostly sunny. High near 74, with temperatures falling to around 72 in the afternoon. Southwest wind 1 to 6 mph.",
-						"relative_humidity": "79%",
-						"wind_speed": "1 to 6 mph SW"
-					},
-					{
-						"name": "Sunday Night",
-						"start_time": "2024-06-09T18:00:00-07:00",
-						"end_time": "2024-06-10T06:00:00-07:00",
-						"temperature": "51F",
-						"detailed_forecast": "Mostly cloudy, with a low around 51. West wind 2 to 6 mph.",
-						"relative_humidity": "85%",
-						"wind_speed": "2 to 6 mph W"
-					}
-				]`,
-			Output: `[{"name":"Tonight","time_of_day":"night","icon":"cloud-moon","beaufort":"Light air"},{"name":"Sunday","time_of_day":"day","icon":"cloud-sun","beaufort":"Light breeze"},{"name":"Sunday Night","time_of_day":"night","icon":"cloud-moon","beaufort":"Light breeze"}]`,
-		},
-	}
-
-	response, err := w.LLMProvider.Complete(timeoutCtx, llm.CompletionRequest{
-		SystemPrompt: systemPrompt,
-		UserPrompt:   buildFinalPrompt(prompt, fewShotTraining, string(periodsJSON)),
-		MaxTokens:    4096,
-	})
+	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	stateJSON, err := json.Marshal(state)
 	if err != nil {
-		slog.Error("worker: failed to get forecast periods information", slog.String("error", err.Error()))
+		slog.Error("worker: could not marshal forecast state", slog.String("error", err.Error()))
 		return
 	}
 
-	var fpi []GetForecastPeriodsInformation
-	cleanedText := stripMarkdownCodeBlock(response.Content)
-	err = json.Unmarshal([]byte(cleanedText), &fpi)
+	err = w.set(timeoutCtx, w.Cache.Key("forecast-state", gridPoint.String()), stateJSON, w.Cache.CacheResultsDuration+w.Cache.CacheStaleDuration)
 	if err != nil {
-		slog.Error("worker: failed to unmarshal forecast periods information", slog.String("error", err.Error()), slog.String("response", cleanedText))
-		return
+		slog.Error("worker: could not set forecast state in cache", slog.String("error", err.Error()))
 	}
+}
 
-	joinedPeriods := make([]JoinedForecastPeriodsInformation, 0)
-	for _, period := range periods {
-		for _, fpiPeriod := range fpi {
-			if period.Name == fpiPeriod.Name {
-				joinedPeriods = append(joinedPeriods, JoinedForecastPeriodsInformation{
-					Name:             fpiPeriod.Name,
-					TimeOfDay:        fpiPeriod.TimeOfDay,
-					Icon:             fpiPeriod.Icon,
-					Beaufort:         fpiPeriod.Beaufort,
-					DetailedForecast: period.DetailedForecast,
-					ShortForecast:    period.ShortForecast,
-					StartTime:        period.StartTime,
-					EndTime:          period.EndTime,
-					Temperature:      period.Temperature,
-					WindSpeed:        period.WindSpeed,
-					WindDirection:    period.WindDirection,
-				})
-			}
-		}
+// archive appends a generated product to the forecast history, fenced by the leader lease like set. The
+// product is already cached, so failures are only logged
+func (w *ForecastWorker) archive(ctx context.Context, gridPoint nws.GridPoint, product string, record history.Record) {
+	var appender history.Appender = w.Cache
+	if lease := leader.LeaseFromContext(ctx); lease != nil {
+		appender = lease
 	}
 
-	fpiResponse := GetForecastPeriodsInformationResponse{
-		Periods:     joinedPeriods,
-		LastUpdated: time.Now(),
+	err := w.History.AppendTo(ctx, appender, gridPoint, product, record)
+	if err != nil {
+		slog.Error("worker: could not archive forecast", slog.String("error", err.Error()), slog.String("grid_point", gridPoint.String()), slog.String("product", product))
 	}
+}
 
-	fpiJson, err := json.Marshal(fpiResponse)
+func (w *ForecastWorker) generateForecastSummary(ctx context.Context, gridPoint nws.GridPoint) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	fsr, err := w.Generator.GenerateForecastSummary(timeoutCtx, gridPoint)
 	if err != nil {
-		slog.Error("worker: failed to marshal forecast periods information", slog.String("error", err.Error()))
-		return
+		return err
 	}
 
-	err = w.DragonflyClient.Client.Set(timeoutCtx, fmt.Sprintf("%s-%s", w.DragonflyClient.KeyPrefix, "forecast-periods-information"), fpiJson, w.DragonflyClient.CacheResultsDuration).Err()
+	fsrJson, err := json.Marshal(fsr)
 	if err != nil {
-		slog.Error("worker: could not set forecast periods information in cache", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to marshal forecast summary: %w", err)
 	}
 
-	slog.Info("worker: forecast periods information generated and cached")
-}
+	err = w.setEntry(timeoutCtx, w.Cache.Key("forecast-summary", gridPoint.String()), cache.NewEntry(fsrJson, w.Cache.CacheResultsDuration))
+	if err != nil {
+		return fmt.Errorf("could not set forecast summary in cache: %w", err)
+	}
 
-// Helper types and functions (duplicated from handlers to avoid circular imports)
+	w.archive(timeoutCtx, gridPoint, history.ProductSummary, history.Record{
+		GeneratedAt:        fsr.LastUpdated,
+		ForecastUpdateTime: fsr.ForecastUpdateTime,
+		Forecast:           fsrJson,
+	})
 
-type multiShot struct {
-	Input  string
-	Output string
+	return nil
 }
 
-func multiShotWrapper(ms []multiShot) string {
-	var sb strings.Builder
-	sb.WriteString("<examples>")
+func (w *ForecastWorker) generateForecastPeriodsInformation(ctx context.Context, gridPoint nws.GridPoint) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
 
-	for _, m := range ms {
-		sb.WriteString("<example>")
-		sb.WriteString("input: ")
-		sb.WriteString(m.Input)
-		sb.WriteString("\noutput: ")
-		sb.WriteString(m.Output)
-		sb.WriteString("</example>")
+	fpiResponse, err := w.Generator.GenerateForecastPeriodsInformation(timeoutCtx, gridPoint)
+	if err != nil {
+		return err
 	}
 
-	sb.WriteString("</examples>")
-	return sb.String()
-}
-
-func buildFinalPrompt(prompt string, ms []multiShot, inputData string) string {
-	return fmt.Sprintf("%s\n\n%s\n\n%s", prompt, multiShotWrapper(ms), fmt.Sprintf("input: %s", inputData))
-}
+	fpiJson, err := json.Marshal(fpiResponse)
+	if err != nil {
+		return fmt.Errorf("failed to marshal forecast periods information: %w", err)
+	}
 
-func stripMarkdownCodeBlock(text string) string {
-	text = strings.TrimSpace(text)
-	if strings.HasPrefix(text, "```json") {
-		text = strings.TrimPrefix(text, "```json")
-	} else if strings.HasPrefix(text, "```") {
-		text = strings.TrimPrefix(text, "```")
+	err = w.setEntry(timeoutCtx, w.Cache.Key("forecast-periods-information", gridPoint.String()), cache.NewEntry(fpiJson, w.Cache.CacheResultsDuration))
+	if err != nil {
+		return fmt.Errorf("could not set forecast periods information in cache: %w", err)
 	}
-	text = strings.TrimSpace(text)
-	text = strings.TrimSuffix(text, "```")
-	return strings.TrimSpace(text)
+
+	w.archive(timeoutCtx, gridPoint, history.ProductDetailed, history.Record{
+		GeneratedAt:        fpiResponse.LastUpdated,
+		ForecastUpdateTime: fpiResponse.ForecastUpdateTime,
+		Forecast:           fpiJson,
+	})
+
+	return nil
 }
@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/locations"
+	"github.com/michaelpeterswa/lfpweather-forecast-inference-api/internal/nws"
+)
+
+const testPromptVersions = "forecast-periods-information@1,forecast-summary@1"
+
+func TestRegenerateReason(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	updated := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	version := nws.ForecastVersion{ETag: `"a"`, UpdateTime: updated}
+	generatedAt := now.Add(-time.Hour)
+	previous := forecastState{Version: version, PromptVersions: testPromptVersions, GeneratedAt: generatedAt}
+
+	tests := []struct {
+		name  string
+		check forecastCheck
+		want  string
+	}{
+		{
+			name:  "not modified",
+			check: forecastCheck{previous: previous, current: version, modified: false},
+			want:  "",
+		},
+		{
+			name:  "re-rendered without an update",
+			check: forecastCheck{previous: previous, current: nws.ForecastVersion{ETag: `"b"`, UpdateTime: updated, GeneratedAt: updated.Add(time.Hour)}, modified: true},
+			want:  "",
+		},
+		{
+			name:  "updated",
+			check: forecastCheck{previous: previous, current: nws.ForecastVersion{ETag: `"b"`, UpdateTime: updated.Add(time.Hour)}, modified: true},
+			want:  RegenerateReasonForecastUpdated,
+		},
+		{
+			name:  "no previous state",
+			check: forecastCheck{previousReason: RegenerateReasonCacheMissing, current: version, modified: true},
+			want:  RegenerateReasonCacheMissing,
+		},
+		{
+			name:  "previous state unreadable",
+			check: forecastCheck{previousReason: RegenerateReasonCheckFailed, current: version, modified: true},
+			want:  RegenerateReasonCheckFailed,
+		},
+		{
+			name:  "nws failed",
+			check: forecastCheck{previous: previous, err: errors.New("503 service unavailable")},
+			want:  RegenerateReasonCheckFailed,
+		},
+		{
+			name:  "nws failed without previous state",
+			check: forecastCheck{previousReason: RegenerateReasonCacheMissing, err: errors.New("503 service unavailable")},
+			want:  RegenerateReasonCheckFailed,
+		},
+		{
+			name:  "prompt changed",
+			check: forecastCheck{previous: forecastState{Version: version, PromptVersions: "forecast-periods-information@1,forecast-summary@0", GeneratedAt: generatedAt}, current: version},
+			want:  RegenerateReasonPromptChanged,
+		},
+		{
+			name:  "updated and prompt changed",
+			check: forecastCheck{previous: forecastState{Version: version, PromptVersions: "forecast-periods-information@1,forecast-summary@0", GeneratedAt: generatedAt}, current: nws.ForecastVersion{UpdateTime: updated.Add(time.Hour)}, modified: true},
+			want:  RegenerateReasonForecastUpdated,
+		},
+		{
+			name:  "just under max staleness",
+			check: forecastCheck{previous: forecastState{Version: version, PromptVersions: testPromptVersions, GeneratedAt: now.Add(-6*time.Hour + time.Second)}, current: version},
+			want:  "",
+		},
+		{
+			name:  "at max staleness",
+			check: forecastCheck{previous: forecastState{Version: version, PromptVersions: testPromptVersions, GeneratedAt: now.Add(-6 * time.Hour)}, current: version},
+			want:  RegenerateReasonMaxStaleness,
+		},
+		{
+			name:  "updated and stale",
+			check: forecastCheck{previous: forecastState{Version: version, PromptVersions: testPromptVersions, GeneratedAt: now.Add(-7 * time.Hour)}, current: nws.ForecastVersion{UpdateTime: updated.Add(time.Hour)}, modified: true},
+			want:  RegenerateReasonForecastUpdated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.regenerateReason(testPromptVersions, 6*time.Hour, now); got != tt.want {
+				t.Errorf("regenerateReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		generatedAt map[string]time.Time
+		want        time.Time
+		wantReason  string
+	}{
+		{name: "no locations", generatedAt: nil, want: now.Add(15 * time.Minute), wantReason: RunReasonPoll},
+		{name: "stale after the next poll", generatedAt: map[string]time.Time{"SEW/127,75": now.Add(-time.Hour)}, want: now.Add(15 * time.Minute), wantReason: RunReasonPoll},
+		{name: "stale before the next poll", generatedAt: map[string]time.Time{"SEW/127,75": now.Add(-5*time.Hour - 55*time.Minute)}, want: now.Add(5 * time.Minute), wantReason: RunReasonMaxStaleness},
+		{
+			name:        "earliest deadline wins",
+			generatedAt: map[string]time.Time{"SEW/127,75": now.Add(-5*time.Hour - 50*time.Minute), "OTX/10,20": now.Add(-5*time.Hour - 55*time.Minute)},
+			want:        now.Add(5 * time.Minute),
+			wantReason:  RunReasonMaxStaleness,
+		},
+		{name: "already stale is retried on the next poll", generatedAt: map[string]time.Time{"SEW/127,75": now.Add(-7 * time.Hour)}, want: now.Add(15 * time.Minute), wantReason: RunReasonPoll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := nextRun(now, 15*time.Minute, 6*time.Hour, tt.generatedAt)
+			if !got.Equal(tt.want) || reason != tt.wantReason {
+				t.Errorf("nextRun() = %v, %q, want %v, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewLocationResult(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	previousGeneratedAt := now.Add(-time.Hour)
+	location := locations.Location{Name: "home", GridPoint: nws.GridPoint{Office: "SEW", X: 127, Y: 75}}
+
+	tests := []struct {
+		name        string
+		summaryErr  error
+		detailedErr error
+		want        LocationResult
+	}{
+		{
+			name: "success",
+			want: LocationResult{Location: location, Reason: RegenerateReasonForecastUpdated, Success: true, GeneratedAt: now, CompletedAt: now},
+		},
+		{
+			name:       "summary failed",
+			summaryErr: errors.New("llm unavailable"),
+			want:       LocationResult{Location: location, Reason: RegenerateReasonForecastUpdated, SummaryError: "llm unavailable", GeneratedAt: previousGeneratedAt, CompletedAt: now},
+		},
+		{
+			name:        "both failed",
+			summaryErr:  errors.New("llm unavailable"),
+			detailedErr: errors.New("nws unavailable"),
+			want:        LocationResult{Location: location, Reason: RegenerateReasonForecastUpdated, SummaryError: "llm unavailable", DetailedError: "nws unavailable", GeneratedAt: previousGeneratedAt, CompletedAt: now},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLocationResult(location, RegenerateReasonForecastUpdated, previousGeneratedAt, tt.summaryErr, tt.detailedErr, now)
+			if got != tt.want {
+				t.Errorf("newLocationResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeRun(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	home := nws.GridPoint{Office: "SEW", X: 127, Y: 75}
+	cabin := nws.GridPoint{Office: "OTX", X: 10, Y: 20}
+	beach := nws.GridPoint{Office: "PQR", X: 30, Y: 40}
+
+	succeeded, skipped, generatedAt := summarizeRun([]LocationResult{
+		{Location: locations.Location{Name: "home", GridPoint: home}, Success: true, GeneratedAt: now},
+		{Location: locations.Location{Name: "cabin", GridPoint: cabin}, Success: true, Skipped: true, GeneratedAt: now.Add(-time.Hour)},
+		{Location: locations.Location{Name: "beach", GridPoint: beach}, SummaryError: "llm unavailable"},
+	})
+
+	if succeeded != 2 || skipped != 1 {
+		t.Errorf("summarizeRun() succeeded, skipped = %d, %d, want 2, 1", succeeded, skipped)
+	}
+
+	// a location that has never been generated is not scheduled by staleness
+	if len(generatedAt) != 2 || !generatedAt[home.String()].Equal(now) || !generatedAt[cabin.String()].Equal(now.Add(-time.Hour)) {
+		t.Errorf("summarizeRun() generatedAt = %v, want home and cabin only", generatedAt)
+	}
+}